@@ -4,48 +4,105 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"linuxvm/internal/sessioncmd"
 	"linuxvm/pkg/define"
 	"linuxvm/pkg/revm"
 	"os"
+	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v3"
 )
 
 func main() {
+	if cmd := sessioncmd.Lookup(os.Args); cmd != nil {
+		if err := sessioncmd.Run(context.Background(), cmd, os.Args); err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := &cli.Command{
 		Name:                      "chroot",
 		Usage:                     "boot a Linux VM with a custom rootfs",
-		UsageText:                 "chroot [flags] <command> [args...]\n   chroot --attach --id <session-id> [--pty] [-- <command> [args...]]",
-		Description:               "boot a Linux microVM using libkrun and execute commands inside it, similar to chroot but with full kernel isolation; use --attach to connect to an existing session",
+		UsageText:                 "chroot [flags] [--] <command> [args...]\n   chroot --detach [flags] <command> [args...]\n   chroot --attach --id <session-id> [--pty] [-- <command> [args...]]\n   chroot ps [--json]\n   chroot clean [--older-than <duration>] [--include-disks] [--dry-run]\n   chroot stop --id <session-id> [--timeout 30s] [--force]\n   chroot logs --id <session-id> [--follow] [--since <duration|time>] [--source host|guest|all]",
+		Description:               "boot a Linux microVM using libkrun and execute commands inside it, similar to chroot but with full kernel isolation; use --attach to connect to an existing session; a first argument of ps, clean, stop or logs runs the session management command of that name, so put -- before a guest command with one of these names, e.g. chroot -- ps aux",
 		DisableSliceFlagSeparator: true,
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: define.FlagRootfs, Usage: "path to a rootfs directory to use as the VM root filesystem; must contain /bin/sh; takes priority over the built-in rootfs"},
 			&cli.Int8Flag{Name: define.FlagCPUS, Usage: "number of vCPU cores to assign to the VM; defaults to host CPU count if unset or less than 1"},
 			&cli.Uint64Flag{Name: define.FlagMemoryInMB, Usage: "VM memory size in MB; minimum 512 MB; defaults to host available memory if unset or less than 512"},
 			&cli.BoolFlag{Name: define.FlagAttachMode, Usage: "attach to an existing VM session instead of booting a new VM; requires --id"},
+			&cli.BoolFlag{Name: define.FlagDetach, Usage: "run the session in the background: wait until the VM is ready, print its endpoints as JSON and exit; launcher output goes to logs/detach.log in the session workspace. A session that exits cleanly before it is seen ready, such as a short command, prints \"finished\": true and no endpoints"},
 			&cli.BoolFlag{Name: define.FlagPTY, Usage: "allocate a pseudo-terminal when attaching and launch an interactive shell"},
 			&cli.StringSliceFlag{Name: define.FlagEnvs, Usage: "environment variables to pass to the guest process (format: KEY=VALUE); can be specified multiple times"},
 			&cli.StringSliceFlag{Name: define.FlagRawDisk, Usage: "attach an ext4 raw disk image to the VM (format: <path>[,uuid=<uuid>][,version=<string>][,mnt=<guest-path>]); auto-created if the file does not exist; new disks default to a random UUID and mount at /mnt/<UUID>; can be specified multiple times"},
 			&cli.StringSliceFlag{Name: define.FlagMount, Usage: "share a host directory into the guest via VirtIO-FS (format: /host/path:/guest/path[,ro]); can be specified multiple times"},
+			&cli.StringSliceFlag{Name: define.FlagPublish, Aliases: []string{"p"}, Usage: "publish a guest port on the host (format: [hostIP:]hostPort:guestPort[/tcp|/udp]); the host IP defaults to 127.0.0.1 and the protocol to tcp; requires --network gvisor; can be specified multiple times"},
 			&cli.BoolFlag{Name: define.FlagUsingSystemProxy, Usage: "read the macOS system HTTP/HTTPS proxy and forward it to the guest as http_proxy/https_proxy env vars; in gvisor mode, 127.0.0.1 is automatically rewritten to host.containers.internal"},
 			&cli.StringFlag{Name: define.FlagWorkDir, Usage: "working directory for command execution inside the guest; the guest-agent chdirs to this path before running the command", Value: "/"},
 			&cli.StringFlag{Name: define.FlagVNetworkType, Usage: "virtual network stack: gvisor uses gvisor-tap-vsock (full TCP/UDP, DNS, NAT via 192.168.127.0/24); tsi uses libkrun transparent socket interception", Value: string(define.GVISOR)},
-			&cli.StringFlag{Name: define.FlagReportEvents, Usage: "HTTP endpoint to receive VM lifecycle events (e.g. unix:///var/run/events.sock or tcp://192.168.1.252:8888)"},
+			&cli.StringSliceFlag{Name: define.FlagReportEvents, Usage: "HTTP endpoint to receive VM lifecycle events (format: <endpoint>[;version=1|2][;kind=<kind>,...][;overflow=block|drop-oldest|spill-to-disk], e.g. unix:///var/run/events.sock, tcp://192.168.1.252:8888 or 'https://collector/events;kind=network_ready,podman_ready'); http(s) endpoints and version=2 select CloudEvents JSON POSTs with batching and retries, user:password@ in an http(s) endpoint is sent as basic auth; kind only reports the listed event kinds; overflow decides what happens to new events while the reporter is behind, block (default) never loses any; can be specified multiple times"},
 			&cli.StringFlag{Name: define.FlagLogLevel, Usage: "log verbosity level (trace, debug, info, warn, error, fatal, panic)", Value: "info"},
-			&cli.StringFlag{Name: define.FlagLogTo, Usage: "custom log file path on host; defaults to /tmp/<session_id>/logs/vm.log when unset"},
-			&cli.StringFlag{Name: define.FlagSessionID, Usage: "required session name; used to derive the workspace directory; sessions with the same name are mutually exclusive via flock", Required: true},
+			&cli.StringFlag{Name: define.FlagLogTo, Usage: "custom host log file path; defaults to logs/revm.log in the session workspace when unset; guest logs always go to logs/vm.log in the session workspace"},
+			&cli.StringFlag{Name: define.FlagSessionID, Usage: "session name; defaults to the config file's sessionID, or a name derived from the directory of the config file; used to derive the workspace directory; sessions with the same name are mutually exclusive via flock"},
 			&cli.StringFlag{Name: define.FlagManageAPIFile, Usage: "custom Unix socket path for the host-side VM management API; defaults to /tmp/<session_id>/socks/vmctl.sock"},
+			&cli.Uint32SliceFlag{Name: define.FlagAllowUID, Usage: "also let this local user connect to the session's management API socket and runtime unix port forwards; root and the launcher's user are always allowed, connections from anyone else are rejected and logged; can be specified multiple times"},
+			&cli.Uint32SliceFlag{Name: define.FlagAllowGID, Usage: "also let members of this local group connect to the session's management API socket and runtime unix port forwards; can be specified multiple times"},
 			&cli.StringFlag{Name: define.FlagExportSSHKeyPrivateFile, Usage: "file path to symlink the generated SSH key to"},
+			&cli.StringFlag{Name: define.FlagConfig, Usage: "load the session definition from a JSON or YAML (.yaml/.yml) file; defaults to the nearest .revm.json, .revm.yaml or .revm.yml found walking up from the current directory; relative host paths in the file resolve against its directory; flags given on the command line override file values, and list flags (--envs, --mount, --publish, --raw-disk, --allow-uid, --allow-gid, --report-events) add to the file's lists"},
 		},
 		Action: func(_ context.Context, command *cli.Command) error {
 			ctx := context.Background()
 
-			cfg := revm.DefaultConfig().
-				WithSessionID(command.String(define.FlagSessionID)).
-				WithLogging(command.String(define.FlagLogLevel), command.String(define.FlagLogTo)).
-				WithPTY(command.Bool(define.FlagPTY))
+			configPath := command.String(define.FlagConfig)
+			if configPath == "" {
+				cwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				if configPath, err = revm.DiscoverConfig(cwd); err != nil {
+					return err
+				}
+				if configPath != "" {
+					logrus.Infof("using project config %q", configPath)
+				}
+			}
+
+			cfg := revm.DefaultConfig()
+			if configPath != "" {
+				loaded, err := revm.LoadConfig(configPath)
+				if err != nil {
+					return err
+				}
+				cfg = loaded
+			}
+
+			if command.IsSet(define.FlagSessionID) {
+				cfg.WithSessionID(command.String(define.FlagSessionID))
+			} else if cfg.SessionID == "" && configPath != "" {
+				abs, err := filepath.Abs(configPath)
+				if err != nil {
+					return err
+				}
+				cfg.WithSessionID(revm.ProjectSessionID(filepath.Dir(abs)))
+			}
+
+			logLevel, logTo := cfg.LogLevel, cfg.LogTo
+			if command.IsSet(define.FlagLogLevel) {
+				logLevel = command.String(define.FlagLogLevel)
+			}
+			if command.IsSet(define.FlagLogTo) {
+				logTo = command.String(define.FlagLogTo)
+			}
+			cfg.WithLogging(logLevel, logTo)
+
+			if command.IsSet(define.FlagPTY) {
+				cfg.WithPTY(command.Bool(define.FlagPTY))
+			}
 
 			if command.Bool(define.FlagAttachMode) {
 				cfg.WithAttach(command.Args().Slice()...)
@@ -59,21 +116,44 @@ func main() {
 				return err
 			}
 
+			if command.IsSet(define.FlagCPUS) {
+				cfg.WithCPUs(int(command.Int8(define.FlagCPUS)))
+			}
+			if command.IsSet(define.FlagMemoryInMB) {
+				cfg.WithMemory(command.Uint64(define.FlagMemoryInMB))
+			}
+			if command.IsSet(define.FlagVNetworkType) {
+				cfg.WithNetwork(command.String(define.FlagVNetworkType))
+			}
+			if command.IsSet(define.FlagUsingSystemProxy) {
+				cfg.WithProxy(command.Bool(define.FlagUsingSystemProxy))
+			}
+			if command.IsSet(define.FlagWorkDir) {
+				cfg.WithWorkDir(command.String(define.FlagWorkDir))
+			}
+
+			// The remaining setters ignore empty values, so file values survive
+			// unless the flag is given.
 			cfg.
-				WithCPUs(int(command.Int8(define.FlagCPUS))).
-				WithMemory(command.Uint64(define.FlagMemoryInMB)).
-				WithNetwork(command.String(define.FlagVNetworkType)).
-				WithProxy(command.Bool(define.FlagUsingSystemProxy)).
 				WithRootfs(command.String(define.FlagRootfs)).
-				WithWorkDir(command.String(define.FlagWorkDir)).
 				WithEnv(command.StringSlice(define.FlagEnvs)...).
 				WithManageAPIFile(command.String(define.FlagManageAPIFile)).
 				WithExportSSHKeyPrivateFile(command.String(define.FlagExportSSHKeyPrivateFile)).
 				WithMount(command.StringSlice(define.FlagMount)...).
-				WithRawDiskSpecs(rawDiskSpecs...)
+				WithPublish(command.StringSlice(define.FlagPublish)...).
+				WithRawDiskSpecs(rawDiskSpecs...).
+				WithAllowUIDs(command.Uint32Slice(define.FlagAllowUID)...).
+				WithAllowGIDs(command.Uint32Slice(define.FlagAllowGID)...).
+				WithReportURL(command.StringSlice(define.FlagReportEvents)...)
 
-			if u := command.String(define.FlagReportEvents); u != "" {
-				cfg.WithEventReporter(u)
+			if command.Bool(define.FlagDetach) && !revm.IsDetachedChild() {
+				session, err := revm.Detach(ctx, cfg, os.Args[1:])
+				if err != nil {
+					return err
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(session)
 			}
 
 			switch cfg.RunMode {
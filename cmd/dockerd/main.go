@@ -4,7 +4,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"linuxvm/internal/sessioncmd"
 	"linuxvm/pkg/define"
 	"linuxvm/pkg/revm"
 	"os"
@@ -14,37 +16,70 @@ import (
 )
 
 func main() {
+	if cmd := sessioncmd.Lookup(os.Args); cmd != nil {
+		if err := sessioncmd.Run(context.Background(), cmd, os.Args); err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	app := &cli.Command{
 		Name:                      "dockerd",
 		Usage:                     "start a Linux VM with the built-in container runtime",
-		UsageText:                 "dockerd [flags]\n   dockerd --attach --id <session-id> [--pty] [-- <command> [args...]]",
+		UsageText:                 "dockerd [flags]\n   dockerd --detach [flags]\n   dockerd --attach --id <session-id> [--pty] [-- <command> [args...]]\n   dockerd ps [--json]\n   dockerd clean [--older-than <duration>] [--include-disks] [--dry-run]\n   dockerd stop --id <session-id> [--timeout 30s] [--force]\n   dockerd logs --id <session-id> [--follow] [--since <duration|time>] [--source host|guest|all]",
 		Description:               "boot a Linux microVM using libkrun with the built-in rootfs and podman container runtime; exposes a Podman-compatible API socket on the host; use --attach to connect to an existing session",
 		DisableSliceFlagSeparator: true,
 		Flags: []cli.Flag{
 			&cli.Int8Flag{Name: define.FlagCPUS, Usage: "number of vCPU cores to assign to the VM; defaults to host CPU count if unset or less than 1"},
 			&cli.Uint64Flag{Name: define.FlagMemoryInMB, Usage: "VM memory size in MB; minimum 512 MB; defaults to host available memory if unset or less than 512"},
 			&cli.BoolFlag{Name: define.FlagAttachMode, Usage: "attach to an existing VM session instead of booting a new VM; requires --id"},
+			&cli.BoolFlag{Name: define.FlagDetach, Usage: "run the session in the background: wait until the VM is ready, print its endpoints as JSON and exit; launcher output goes to logs/detach.log in the session workspace. A session that exits cleanly before it is seen ready, such as a short command, prints \"finished\": true and no endpoints"},
 			&cli.BoolFlag{Name: define.FlagPTY, Usage: "allocate a pseudo-terminal when attaching and launch an interactive shell"},
 			&cli.StringSliceFlag{Name: define.FlagEnvs, Usage: "environment variables to pass to the guest process (format: KEY=VALUE); can be specified multiple times"},
 			&cli.StringSliceFlag{Name: define.FlagRawDisk, Usage: "attach an ext4 raw disk image to the VM (format: <path>[,uuid=<uuid>][,version=<string>][,mnt=<guest-path>]); auto-created if the file does not exist; new disks default to a random UUID and mount at /mnt/<UUID>; can be specified multiple times"},
 			&cli.StringSliceFlag{Name: define.FlagMount, Usage: "share a host directory into the guest via VirtIO-FS (format: /host/path:/guest/path[,ro]); can be specified multiple times"},
 			&cli.BoolFlag{Name: define.FlagUsingSystemProxy, Usage: "read the macOS system HTTP/HTTPS proxy and forward it to the guest as http_proxy/https_proxy env vars; in gvisor mode, 127.0.0.1 is automatically rewritten to host.containers.internal"},
-			&cli.StringFlag{Name: define.FlagReportEvents, Usage: "HTTP endpoint to receive VM lifecycle events (e.g. unix:///var/run/events.sock or tcp://192.168.1.252:8888)"},
+			&cli.StringSliceFlag{Name: define.FlagReportEvents, Usage: "HTTP endpoint to receive VM lifecycle events (format: <endpoint>[;version=1|2][;kind=<kind>,...][;overflow=block|drop-oldest|spill-to-disk], e.g. unix:///var/run/events.sock, tcp://192.168.1.252:8888 or 'https://collector/events;kind=network_ready,podman_ready'); http(s) endpoints and version=2 select CloudEvents JSON POSTs with batching and retries, user:password@ in an http(s) endpoint is sent as basic auth; kind only reports the listed event kinds; overflow decides what happens to new events while the reporter is behind, block (default) never loses any; can be specified multiple times"},
 			&cli.StringFlag{Name: define.FlagLogLevel, Usage: "log verbosity level (trace, debug, info, warn, error, fatal, panic)", Value: "info"},
-			&cli.StringFlag{Name: define.FlagLogTo, Usage: "custom log file path on host; defaults to /tmp/<session_id>/logs/vm.log when unset"},
-			&cli.StringFlag{Name: define.FlagSessionID, Usage: "required session name; used to derive the workspace directory; sessions with the same name are mutually exclusive via flock", Required: true},
+			&cli.StringFlag{Name: define.FlagLogTo, Usage: "custom host log file path; defaults to logs/revm.log in the session workspace when unset; guest logs always go to logs/vm.log in the session workspace"},
+			&cli.StringFlag{Name: define.FlagSessionID, Usage: "session name, required unless set by --config; used to derive the workspace directory; sessions with the same name are mutually exclusive via flock"},
 			&cli.StringFlag{Name: define.FlagContainerDisk, Usage: "persistent ext4 raw disk image for container storage (format: <path>[,version=<string>]); auto-created if missing; if the stored version xattr is missing or mismatched, the disk is recreated; defaults to a workspace-local disk with the built-in container disk version when unset"},
 			&cli.StringFlag{Name: define.FlagPodmanProxyAPIFile, Usage: "custom Unix socket path for the host-side Podman API proxy; defaults to /tmp/<session_id>/socks/podman-api.sock"},
 			&cli.StringFlag{Name: define.FlagManageAPIFile, Usage: "custom Unix socket path for the host-side VM management API; defaults to /tmp/<session_id>/socks/vmctl.sock"},
+			&cli.Uint32SliceFlag{Name: define.FlagAllowUID, Usage: "also let this local user connect to the session's management and Podman API sockets and runtime unix port forwards; root and the launcher's user are always allowed, connections from anyone else are rejected and logged; can be specified multiple times"},
+			&cli.Uint32SliceFlag{Name: define.FlagAllowGID, Usage: "also let members of this local group connect to the session's management and Podman API sockets and runtime unix port forwards; can be specified multiple times"},
 			&cli.StringFlag{Name: define.FlagExportSSHKeyPrivateFile, Usage: "file path to symlink the generated SSH key to"},
+			&cli.StringFlag{Name: define.FlagConfig, Usage: "load the session definition from a JSON or YAML (.yaml/.yml) file; flags given on the command line override file values, and list flags (--envs, --mount, --raw-disk, --allow-uid, --allow-gid, --report-events) add to the file's lists"},
 		},
 		Action: func(_ context.Context, command *cli.Command) error {
 			ctx := context.Background()
 
-			cfg := revm.DefaultConfig().
-				WithSessionID(command.String(define.FlagSessionID)).
-				WithLogging(command.String(define.FlagLogLevel), command.String(define.FlagLogTo)).
-				WithPTY(command.Bool(define.FlagPTY))
+			cfg := revm.DefaultConfig()
+			if path := command.String(define.FlagConfig); path != "" {
+				loaded, err := revm.LoadConfig(path)
+				if err != nil {
+					return err
+				}
+				cfg = loaded
+			}
+
+			if command.IsSet(define.FlagSessionID) {
+				cfg.WithSessionID(command.String(define.FlagSessionID))
+			}
+
+			logLevel, logTo := cfg.LogLevel, cfg.LogTo
+			if command.IsSet(define.FlagLogLevel) {
+				logLevel = command.String(define.FlagLogLevel)
+			}
+			if command.IsSet(define.FlagLogTo) {
+				logTo = command.String(define.FlagLogTo)
+			}
+			cfg.WithLogging(logLevel, logTo)
+
+			if command.IsSet(define.FlagPTY) {
+				cfg.WithPTY(command.Bool(define.FlagPTY))
+			}
 
 			if command.Bool(define.FlagAttachMode) {
 				cfg.WithAttach(command.Args().Slice()...)
@@ -67,21 +102,39 @@ func main() {
 				containerDiskSpec = &spec
 			}
 
+			if command.IsSet(define.FlagCPUS) {
+				cfg.WithCPUs(int(command.Int8(define.FlagCPUS)))
+			}
+			if command.IsSet(define.FlagMemoryInMB) {
+				cfg.WithMemory(command.Uint64(define.FlagMemoryInMB))
+			}
+			if command.IsSet(define.FlagUsingSystemProxy) {
+				cfg.WithProxy(command.Bool(define.FlagUsingSystemProxy))
+			}
+
+			// The remaining setters ignore empty values, so file values survive
+			// unless the flag is given.
 			cfg.
-				WithCPUs(int(command.Int8(define.FlagCPUS))).
-				WithMemory(command.Uint64(define.FlagMemoryInMB)).
 				WithNetwork(string(define.GVISOR)).
-				WithProxy(command.Bool(define.FlagUsingSystemProxy)).
 				WithEnv(command.StringSlice(define.FlagEnvs)...).
 				WithMount(command.StringSlice(define.FlagMount)...).
 				WithContainerDiskSpec(containerDiskSpec).
 				WithPodmanProxyAPIFile(command.String(define.FlagPodmanProxyAPIFile)).
 				WithManageAPIFile(command.String(define.FlagManageAPIFile)).
 				WithExportSSHKeyPrivateFile(command.String(define.FlagExportSSHKeyPrivateFile)).
-				WithRawDiskSpecs(rawDiskSpecs...)
+				WithRawDiskSpecs(rawDiskSpecs...).
+				WithAllowUIDs(command.Uint32Slice(define.FlagAllowUID)...).
+				WithAllowGIDs(command.Uint32Slice(define.FlagAllowGID)...).
+				WithReportURL(command.StringSlice(define.FlagReportEvents)...)
 
-			if u := command.String(define.FlagReportEvents); u != "" {
-				cfg.WithEventReporter(u)
+			if command.Bool(define.FlagDetach) && !revm.IsDetachedChild() {
+				session, err := revm.Detach(ctx, cfg, os.Args[1:])
+				if err != nil {
+					return err
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(session)
 			}
 
 			switch cfg.RunMode {
@@ -139,6 +139,7 @@ func main() {
 		UsageText:                 os.Args[0] + " [command] [flags]",
 		Description:               "setup the guest environment, and run the command specified by the user.",
 		Action:                    run,
+		Commands:                  []*cli.Command{statsCommand()},
 		DisableSliceFlagSeparator: true,
 	}
 
@@ -188,6 +189,7 @@ func run(ctx context.Context, _ *cli.Command) error {
 	if err := service.MountVirtiofs(ctx, vmc); err != nil {
 		return fmt.Errorf("mount virtiofs: %w", err)
 	}
+	service.ReportMountsReady(ctx)
 	go func() {
 		service.WaitAndShutdown()
 	}()
@@ -82,6 +82,7 @@ func (d *Dropbear) Start(ctx context.Context) {
 		Stderr:     StderrWriter(),
 		Restart:    true,
 		RetryDelay: 500 * time.Millisecond,
+		OnRestart:  reportRestarts(ctx, "dropbear"),
 	})
 	sv.Run(ctx)
 }
@@ -105,6 +106,7 @@ func StartGuestSSHServer(ctx context.Context, vmc *protocol.GuestSpec) error {
 		return fmt.Errorf("write authorized_keys: %w", err)
 	}
 
+	go reportWhenListening(ctx, cfg.ListenAddr, protocol.GuestEventSSHReady)
 	dropbear.Start(ctx)
 	return nil
 }
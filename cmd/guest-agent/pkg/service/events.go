@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"guestAgent/pkg/vsock"
+	"linuxvm/pkg/protocol"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReportEvent relays a guest milestone to the host event stream. Like
+// ReportMountsReady it is best effort.
+func ReportEvent(ctx context.Context, evt protocol.GuestEvent) {
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+
+	svc := vsock.NewVSockService()
+	defer svc.Close()
+
+	if err := svc.ReportEvent(ctx, evt); err != nil {
+		logrus.Warnf("report guest event %s: %v", evt.Kind, err)
+	}
+}
+
+// restartReportQueueSize bounds the restart events waiting for the host.
+const restartReportQueueSize = 16
+
+// reportRestarts returns a supervisor OnRestart hook that reports each
+// restart of name. The hook never waits for the host: events are sent in the
+// background and dropped while restartReportQueueSize are still pending.
+func reportRestarts(ctx context.Context, name string) func(int, error) {
+	queue := make(chan protocol.GuestEvent, restartReportQueueSize)
+	var start sync.Once
+
+	return func(restarts int, err error) {
+		start.Do(func() {
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case evt := <-queue:
+						ReportEvent(ctx, evt)
+					}
+				}
+			}()
+		})
+
+		msg := fmt.Sprintf("%s restarted (restart %d)", name, restarts)
+		if err != nil {
+			msg = fmt.Sprintf("%s restarted after %v (restart %d)", name, err, restarts)
+		}
+		select {
+		case queue <- protocol.GuestEvent{Kind: protocol.GuestEventServiceRestarted, Message: msg, Time: time.Now()}:
+		default:
+			logrus.Warnf("report guest event %s: queue full, dropping %q", protocol.GuestEventServiceRestarted, msg)
+		}
+	}
+}
+
+// reportWhenListening reports kind once addr accepts TCP connections.
+func reportWhenListening(ctx context.Context, addr string, kind protocol.GuestEventKind) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			_ = conn.Close()
+			ReportEvent(ctx, protocol.GuestEvent{Kind: kind, Message: addr + " is listening"})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
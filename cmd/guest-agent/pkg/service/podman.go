@@ -19,7 +19,8 @@ func StartGuestPodmanService(ctx context.Context, vmc *protocol.GuestSpec) error
 	}
 
 	s := supervisor.New(supervisor.Config{
-		Cmd: "podman",
+		Name: "podman",
+		Cmd:  "podman",
 		Args: []string{
 			"--log-level", logrus.GetLevel().String(), "system", "service",
 			"--time=0", fmt.Sprintf("tcp://%s", vmc.Podman.GuestPodmanAPIListenAddr),
@@ -29,6 +30,7 @@ func StartGuestPodmanService(ctx context.Context, vmc *protocol.GuestSpec) error
 		RetryDelay:  500 * time.Millisecond,
 		StopTimeout: 5 * time.Second,
 		Env:         vmc.Podman.GuestPodmanRunWithEnvs,
+		OnRestart:   reportRestarts(ctx, "podman"),
 	})
 
 	s.Run(ctx)
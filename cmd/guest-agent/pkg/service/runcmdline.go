@@ -77,5 +77,22 @@ func DoExecCmdLine(ctx context.Context, vmc *protocol.GuestSpec) error {
 
 	cmd.Env = append(os.Environ(), vmc.Cmdline.Envs...)
 
-	return cmd.Run()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	ReportEvent(ctx, protocol.GuestEvent{
+		Kind:    protocol.GuestEventCommandStarted,
+		Message: fmt.Sprintf("%s started, pid %d", vmc.Cmdline.Bin, cmd.Process.Pid),
+	})
+
+	err := cmd.Wait()
+	exitCode := cmd.ProcessState.ExitCode()
+	// The VM powers off once the command returns, so this one is sent even
+	// if ctx is already cancelled.
+	ReportEvent(context.WithoutCancel(ctx), protocol.GuestEvent{
+		Kind:     protocol.GuestEventCommandExited,
+		Message:  fmt.Sprintf("%s exited: %s", vmc.Cmdline.Bin, cmd.ProcessState),
+		ExitCode: &exitCode,
+	})
+	return err
 }
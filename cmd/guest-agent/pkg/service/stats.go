@@ -0,0 +1,261 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/protocol"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// statsSampleWindow is how long a single stats snapshot measures CPU usage.
+const statsSampleWindow = 500 * time.Millisecond
+
+// WriteStats writes guest resource statistics to w as JSON lines. With a zero
+// interval a single snapshot is written, otherwise one per interval until ctx
+// is done.
+func WriteStats(ctx context.Context, w io.Writer, interval time.Duration) error {
+	disks := statsBlockDevices()
+	enc := json.NewEncoder(w)
+
+	window := interval
+	if window <= 0 {
+		window = statsSampleWindow
+	}
+
+	prev, err := readCPUTimes()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := readCPUTimes()
+		if err != nil {
+			return err
+		}
+		stats, err := collectStats(prev, cur, disks)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(stats); err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return nil
+		}
+		prev = cur
+	}
+}
+
+func collectStats(prev, cur cpuTimes, disks []protocol.GuestBlockDev) (*protocol.GuestStats, error) {
+	stats := &protocol.GuestStats{
+		Time: time.Now(),
+		CPU:  cur.usage(prev),
+	}
+
+	var err error
+	if stats.Memory, err = readMemInfo(); err != nil {
+		return nil, err
+	}
+	if stats.Load, err = readLoadAvg(); err != nil {
+		return nil, err
+	}
+	if stats.Processes, err = countProcesses(); err != nil {
+		return nil, err
+	}
+	stats.Cgroup = readCgroupStats()
+
+	for _, dev := range disks {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(dev.MountTo, &st); err != nil {
+			continue
+		}
+		bsize := uint64(st.Bsize) //nolint:gosec
+		stats.Disks = append(stats.Disks, protocol.GuestDisk{
+			Path:           dev.Path,
+			MountPoint:     dev.MountTo,
+			FsType:         dev.FsType,
+			TotalBytes:     st.Blocks * bsize,
+			UsedBytes:      (st.Blocks - st.Bfree) * bsize,
+			AvailableBytes: st.Bavail * bsize,
+			InodesTotal:    st.Files,
+			InodesFree:     st.Ffree,
+		})
+	}
+	return stats, nil
+}
+
+// statsBlockDevices returns the block devices from the vmconfig written at boot.
+func statsBlockDevices() []protocol.GuestBlockDev {
+	data, err := os.ReadFile(define.VMConfigFilePathInGuest)
+	if err != nil {
+		return nil
+	}
+	var vmc protocol.GuestSpec
+	if err := json.Unmarshal(data, &vmc); err != nil {
+		return nil
+	}
+	return vmc.BlkDevs
+}
+
+// cpuTimes holds the aggregate "cpu" line of /proc/stat, in clock ticks.
+type cpuTimes struct {
+	cores                                                 int
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+func (c cpuTimes) total() uint64 {
+	return c.user + c.nice + c.system + c.idle + c.iowait + c.irq + c.softirq + c.steal
+}
+
+func (c cpuTimes) usage(prev cpuTimes) protocol.GuestCPU {
+	usage := protocol.GuestCPU{Cores: c.cores}
+	total := float64(c.total() - prev.total())
+	if total <= 0 {
+		return usage
+	}
+	percent := func(cur, old uint64) float64 {
+		return float64(cur-old) / total * 100
+	}
+	usage.User = percent(c.user+c.nice, prev.user+prev.nice)
+	usage.System = percent(c.system+c.irq+c.softirq, prev.system+prev.irq+prev.softirq)
+	usage.IOWait = percent(c.iowait, prev.iowait)
+	usage.Steal = percent(c.steal, prev.steal)
+	usage.UsagePercent = 100 - percent(c.idle+c.iowait, prev.idle+prev.iowait)
+	return usage
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, fmt.Errorf("read cpu stats: %w", err)
+	}
+	defer f.Close()
+
+	var times cpuTimes
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			times.cores++
+			continue
+		}
+		values := make([]uint64, 8)
+		for i := range values {
+			if i+1 < len(fields) {
+				values[i], _ = strconv.ParseUint(fields[i+1], 10, 64)
+			}
+		}
+		times.user, times.nice, times.system, times.idle = values[0], values[1], values[2], values[3]
+		times.iowait, times.irq, times.softirq, times.steal = values[4], values[5], values[6], values[7]
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, fmt.Errorf("read cpu stats: %w", err)
+	}
+	return times, nil
+}
+
+func readMemInfo() (protocol.GuestMemory, error) {
+	values, err := readKeyValueFile("/proc/meminfo")
+	if err != nil {
+		return protocol.GuestMemory{}, fmt.Errorf("read memory stats: %w", err)
+	}
+	// /proc/meminfo reports kB.
+	kb := func(key string) uint64 { return values[key] * 1024 }
+
+	mem := protocol.GuestMemory{
+		TotalBytes:     kb("MemTotal:"),
+		AvailableBytes: kb("MemAvailable:"),
+		FreeBytes:      kb("MemFree:"),
+		CachedBytes:    kb("Cached:") + kb("Buffers:"),
+		SwapTotalBytes: kb("SwapTotal:"),
+		SwapFreeBytes:  kb("SwapFree:"),
+	}
+	if mem.TotalBytes > mem.AvailableBytes {
+		mem.UsedBytes = mem.TotalBytes - mem.AvailableBytes
+	}
+	return mem, nil
+}
+
+func readLoadAvg() (protocol.GuestLoad, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return protocol.GuestLoad{}, fmt.Errorf("read load average: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return protocol.GuestLoad{}, fmt.Errorf("unexpected /proc/loadavg content %q", data)
+	}
+	var load protocol.GuestLoad
+	load.Load1, _ = strconv.ParseFloat(fields[0], 64)
+	load.Load5, _ = strconv.ParseFloat(fields[1], 64)
+	load.Load15, _ = strconv.ParseFloat(fields[2], 64)
+	return load, nil
+}
+
+func countProcesses() (int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, fmt.Errorf("list processes: %w", err)
+	}
+	n := 0
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// readCgroupStats reads the root cgroup v2 accounting. The root cgroup has no
+// memory.current, so memory comes from memory.stat.
+func readCgroupStats() *protocol.GuestCgroup {
+	cpu, err := readKeyValueFile("/sys/fs/cgroup/cpu.stat")
+	if err != nil {
+		return nil
+	}
+	stats := &protocol.GuestCgroup{CPUUsageUsec: cpu["usage_usec"]}
+	if mem, err := readKeyValueFile("/sys/fs/cgroup/memory.stat"); err == nil {
+		stats.MemoryAnonBytes = mem["anon"]
+		stats.MemoryFileBytes = mem["file"]
+	}
+	return stats
+}
+
+// readKeyValueFile parses "key value [unit]" lines.
+func readKeyValueFile(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for line := range bytes.Lines(data) {
+		fields := strings.Fields(string(line))
+		if len(fields) < 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
@@ -57,3 +57,14 @@ func WriteToJsonFile(vmc *protocol.GuestSpec, file string) error {
 
 	return os.WriteFile(file, b, 0644)
 }
+
+// ReportMountsReady lets the host health check know that mounting is done.
+// It is best effort: the guest keeps running if the host does not answer.
+func ReportMountsReady(ctx context.Context) {
+	svc := vsock.NewVSockService()
+	defer svc.Close()
+
+	if err := svc.ReportMountsReady(ctx); err != nil {
+		logrus.Warnf("report mounts ready: %v", err)
+	}
+}
@@ -27,6 +27,10 @@ type Config struct {
 	RetryDelay time.Duration // 重启间隔
 
 	StopTimeout time.Duration
+
+	// OnRestart is called before each restart with the number of restarts so
+	// far, including this one, and the error the process exited with.
+	OnRestart func(restarts int, err error)
 }
 
 type Supervisor struct {
@@ -70,6 +74,9 @@ func (s *Supervisor) loop(ctx context.Context) {
 			logrus.Infof("[supervisor:%s] max retries reached", s.cfg.Name)
 			return
 		}
+		if s.cfg.OnRestart != nil {
+			s.cfg.OnRestart(s.restarts, err)
+		}
 
 		select {
 		case <-ctx.Done():
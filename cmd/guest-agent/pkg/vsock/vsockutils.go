@@ -3,6 +3,7 @@
 package vsock
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"linuxvm/pkg/network"
 	"linuxvm/pkg/protocol"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -29,8 +31,43 @@ func NewVSockService() *Service {
 	}
 }
 
+// hostVersion returns the version info of the host. Hosts that predate the
+// version route are reported as protocol.LegacyVersionInfo.
+func (v *Service) hostVersion(ctx context.Context) (protocol.VersionInfo, error) {
+	body, status, err := v.client.Get(define.RestAPIVersionURL).DoAndRead(ctx)
+	if err != nil {
+		return protocol.VersionInfo{}, err
+	}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return protocol.LegacyVersionInfo(), nil
+	default:
+		return protocol.VersionInfo{}, fmt.Errorf("GET version returned %d", status)
+	}
+
+	var info protocol.VersionInfo
+	if err = json.Unmarshal(body, &info); err != nil {
+		return protocol.VersionInfo{}, fmt.Errorf("failed to unmarshal version: %w", err)
+	}
+	return info, nil
+}
+
+// GetVMConfig fetches the guest spec in the newest schema version supported
+// by both the host and this agent.
 func (v *Service) GetVMConfig(ctx context.Context) (*protocol.GuestSpec, error) {
-	body, status, err := v.client.Get(define.RestAPIVMConfigURL).DoAndRead(ctx)
+	info, err := v.hostVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := protocol.GuestSpecRange.Negotiate(info.Schemas.GuestSpec)
+	if err != nil {
+		return nil, fmt.Errorf("negotiate guest spec version: %w", err)
+	}
+
+	body, status, err := v.client.Get(define.RestAPIVMConfigURL).
+		Query(protocol.SchemaVersionQuery, strconv.Itoa(version)).
+		DoAndRead(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +79,43 @@ func (v *Service) GetVMConfig(ctx context.Context) (*protocol.GuestSpec, error)
 	if err = json.Unmarshal(body, vmc); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal vmconfig: %w", err)
 	}
-	if vmc.SchemaVersion != protocol.GuestSpecVersion {
-		return nil, fmt.Errorf("unsupported guest spec schema version: %d", vmc.SchemaVersion)
+	if !protocol.GuestSpecRange.Contains(vmc.SchemaVersion) {
+		return nil, fmt.Errorf("host returned guest spec version %d, supported %d-%d",
+			vmc.SchemaVersion, protocol.GuestSpecRange.Min, protocol.GuestSpecRange.Max)
 	}
 
 	return vmc, nil
 }
+
+// ReportMountsReady tells the host that all disks and shares are mounted.
+func (v *Service) ReportMountsReady(ctx context.Context) error {
+	_, status, err := v.client.Post(define.RestAPIMountsReadyURL).DoAndRead(ctx)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("POST mounts-ready returned %d", status)
+	}
+	return nil
+}
+
+// ReportEvent posts evt to the host event stream. Hosts that predate guest
+// events answer 404, which is not an error.
+func (v *Service) ReportEvent(ctx context.Context, evt protocol.GuestEvent) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal guest event: %w", err)
+	}
+
+	_, status, err := v.client.Post(define.RestAPIGuestEventsURL).
+		Header("Content-Type", "application/json").
+		Body(bytes.NewReader(body)).
+		DoAndRead(ctx)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK && status != http.StatusNotFound {
+		return fmt.Errorf("POST events returned %d", status)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"guestAgent/pkg/service"
+	"linuxvm/pkg/protocol"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v3"
+)
+
+// statsCommand prints guest resource statistics. The host runs it over SSH
+// to serve /v2/stats.
+func statsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  protocol.GuestStatsCommand,
+		Usage: "print guest resource statistics as JSON lines",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: protocol.GuestStatsIntervalFlag, Usage: "print a new sample every interval until interrupted, 0 prints one sample"},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+			defer stop()
+			return service.WriteStats(ctx, os.Stdout, command.Duration(protocol.GuestStatsIntervalFlag))
+		},
+	}
+}
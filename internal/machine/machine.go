@@ -54,9 +54,12 @@ func (m *Machine) ManagementAPIEndpoint() string {
 	return m.spec.VMCtlAddr
 }
 
-func (m *Machine) GuestSpec() protocol.GuestSpec {
+// GuestSpec returns the guest spec in the given schema version. Every version
+// in protocol.GuestSpecRange has the same fields so far; a version that
+// changes them is converted here.
+func (m *Machine) GuestSpec(version int) protocol.GuestSpec {
 	return protocol.GuestSpec{
-		SchemaVersion: protocol.GuestSpecVersion,
+		SchemaVersion: version,
 		RunMode:       m.spec.RunMode,
 		NetworkMode:   string(m.spec.VirtualNetworkMode),
 		TTY:           m.spec.TTY,
@@ -68,10 +71,13 @@ func (m *Machine) GuestSpec() protocol.GuestSpec {
 	}
 }
 
-func (m *Machine) AttachSpec() protocol.AttachSpec {
+// AttachSpec returns the attach spec in the given schema version. Every
+// version in protocol.AttachSpecRange has the same fields so far; a version
+// that changes them is converted here.
+func (m *Machine) AttachSpec(version int) protocol.AttachSpec {
 	sshTarget := m.SSHTarget()
 	return protocol.AttachSpec{
-		SchemaVersion:            protocol.AttachSpecVersion,
+		SchemaVersion:            version,
 		User:                     sshTarget.User,
 		PrivateKeyFile:           sshTarget.PrivateKeyFile,
 		UseGVProxyTunnel:         sshTarget.UseGVProxyTunnel,
@@ -137,6 +143,7 @@ func (m *Machine) GVProxySpec() gvproxy.Spec {
 		GuestSSHListenAddr:  m.spec.SSHInfo.GuestSSHServerListenAddr,
 		GuestIP:             define.GuestIP,
 		HostLoopbackAddress: define.LocalHost,
+		PortForwards:        m.spec.PortForwards,
 	}
 }
 
@@ -0,0 +1,98 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package sessioncmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/revm"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+)
+
+func cleanCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "clean",
+		Aliases:     []string{"gc"},
+		Usage:       "remove the leftovers of sessions that are no longer running",
+		UsageText:   "clean [--older-than <duration>] [--include-disks] [--dry-run] [--json]",
+		Description: "acquire each session lock and, for sessions nobody holds, remove the extracted rootfs, stale sockets, SSH keys and event spill files; logs are kept",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: define.FlagOlderThan, Usage: "only clean sessions not used within this duration, e.g. 72h"},
+			&cli.BoolFlag{Name: define.FlagIncludeDisks, Usage: "also remove the built-in container storage disk (raw-disk/container-storage.ext4)"},
+			&cli.BoolFlag{Name: define.FlagDryRun, Usage: "print what would be removed without removing anything"},
+			&cli.BoolFlag{Name: define.FlagJSON, Usage: "print results as JSON instead of a table"},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			opts := revm.CleanOptions{
+				OlderThan:    command.Duration(define.FlagOlderThan),
+				IncludeDisks: command.Bool(define.FlagIncludeDisks),
+				DryRun:       command.Bool(define.FlagDryRun),
+			}
+
+			results, err := revm.CleanSessions(ctx, opts)
+			if command.Bool(define.FlagJSON) {
+				if jsonErr := writeJSON(os.Stdout, results); jsonErr != nil && err == nil {
+					err = jsonErr
+				}
+				return err
+			}
+			if tableErr := writeCleanTable(os.Stdout, results, opts.DryRun); tableErr != nil && err == nil {
+				err = tableErr
+			}
+			return err
+		},
+	}
+}
+
+func writeCleanTable(w io.Writer, results []revm.CleanResult, dryRun bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLAST USED\tRESULT\tRECLAIMED")
+
+	var total int64
+	for _, r := range results {
+		result := "cleaned"
+		switch {
+		case r.Skipped != "":
+			result = "skipped (" + r.Skipped + ")"
+		case len(r.Removed) == 0:
+			result = "nothing to remove"
+		case dryRun:
+			result = "would clean"
+		}
+		total += r.ReclaimedBytes
+
+		lastUsed := "-"
+		if !r.LastUsed.IsZero() {
+			lastUsed = r.LastUsed.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.ID, lastUsed, result, formatBytes(r.ReclaimedBytes))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	verb := "reclaimed"
+	if dryRun {
+		verb = "would reclaim"
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", verb, formatBytes(total))
+	return err
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,62 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package sessioncmd
+
+import (
+	"context"
+	"fmt"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/revm"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "logs",
+		Usage:       "print the host and guest logs of a session",
+		UsageText:   "logs --id <session-id> [--follow] [--since <duration|time>] [--source host|guest|all]",
+		Description: "read logs/revm.log (host) and logs/vm.log (guest) from the session workspace and merge them in timestamp order",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: define.FlagSessionID, Usage: "name of the session", Required: true},
+			&cli.BoolFlag{Name: define.FlagFollow, Aliases: []string{"f"}, Usage: "keep printing new log lines until interrupted; follows the logs across truncation and session restarts"},
+			&cli.StringFlag{Name: define.FlagSince, Usage: "only print lines logged after this point, either a duration such as 10m or a time such as 2006-01-02T15:04:05Z07:00"},
+			&cli.StringFlag{Name: define.FlagLogSource, Usage: "which logs to print: host, guest or all", Value: string(revm.LogSourceAll)},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			since, err := parseSince(command.String(define.FlagSince), time.Now())
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			return revm.Logs(ctx, command.String(define.FlagSessionID), revm.LogOptions{
+				Source: revm.LogSource(command.String(define.FlagLogSource)),
+				Since:  since,
+				Follow: command.Bool(define.FlagFollow),
+			}, os.Stdout)
+		},
+	}
+}
+
+// parseSince accepts a duration relative to now or an absolute time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s value %q: want a duration or a time", define.FlagSince, value)
+}
@@ -0,0 +1,92 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package sessioncmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/revm"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func psCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "ps",
+		Aliases:     []string{"list"},
+		Usage:       "list running and stale sessions",
+		UsageText:   "ps [--json]",
+		Description: "walk the session root, probe each session lock for liveness and query the management API of running sessions",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: define.FlagJSON, Usage: "print sessions as JSON instead of a table"},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			sessions, err := revm.ListSessions(ctx)
+			if err != nil {
+				return err
+			}
+			if command.Bool(define.FlagJSON) {
+				return writeJSON(os.Stdout, sessions)
+			}
+			return writeSessionTable(os.Stdout, sessions)
+		},
+	}
+}
+
+func writeSessionTable(w io.Writer, sessions []revm.SessionInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATE\tMODE\tCPUS\tMEMORY\tUPTIME\tSSH\tPODMAN\tMOUNTS")
+	for _, s := range sessions {
+		mode, cpus, memory, ssh, podman, mounts := "-", "-", "-", "-", "-", "-"
+		if v := s.VMConfig; v != nil {
+			mode = orDash(v.RunMode)
+			cpus = fmt.Sprintf("%d", v.Resources.CPUs)
+			memory = fmt.Sprintf("%dMB", v.Resources.MemoryInMB)
+			ssh = orDash(v.Endpoints.SSH)
+			podman = orDash(v.Endpoints.PodmanAPI)
+
+			var specs []string
+			for _, m := range v.Mounts {
+				spec := m.Source + ":" + m.Target
+				if m.ReadOnly {
+					spec += ",ro"
+				}
+				specs = append(specs, spec)
+			}
+			mounts = orDash(strings.Join(specs, " "))
+		}
+
+		state := string(s.State)
+		if s.VMConfig != nil && s.VMConfig.State.State != "" {
+			// A running session reports where its VM is in its lifecycle.
+			state = string(s.VMConfig.State.State)
+		}
+		if s.Error != "" {
+			state += " (" + s.Error + ")"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID, state, mode, cpus, memory, formatUptime(s.Uptime()), ssh, podman, mounts)
+	}
+	return tw.Flush()
+}
+
+func formatUptime(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Truncate(time.Second).String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+// Package sessioncmd implements the session management subcommands shared by
+// the chroot and dockerd launchers.
+//
+// The launchers treat their positional arguments as the guest command line, so
+// these subcommands are dispatched before the launcher flags are parsed and
+// only when the very first argument names one of them. A guest command of the
+// same name is run by putting -- in front of it.
+package sessioncmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/urfave/cli/v3"
+)
+
+func commands() []*cli.Command {
+	return []*cli.Command{
+		psCommand(),
+		cleanCommand(),
+		stopCommand(),
+		logsCommand(),
+	}
+}
+
+// Lookup returns the session management command named by args[1], or nil if
+// args should be handled by the launcher itself.
+func Lookup(args []string) *cli.Command {
+	if len(args) < 2 {
+		return nil
+	}
+	for _, cmd := range commands() {
+		if cmd.HasName(args[1]) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Run executes the session management command selected by Lookup.
+func Run(ctx context.Context, cmd *cli.Command, args []string) error {
+	return cmd.Run(ctx, args[1:])
+}
+
+func writeJSON(w io.Writer, value any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
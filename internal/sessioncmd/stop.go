@@ -0,0 +1,41 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package sessioncmd
+
+import (
+	"context"
+	"fmt"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/revm"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func stopCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "stop",
+		Usage:       "stop a running session",
+		UsageText:   "stop --id <session-id> [--timeout 30s] [--force]",
+		Description: "ask the guest to shut down through the management API and wait until the launcher releases the session lock; the launcher escalates to a force stop when the timeout expires. Prints how the VM ended: guest, graceful or forced",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: define.FlagSessionID, Usage: "name of the session to stop", Required: true},
+			&cli.DurationFlag{Name: define.FlagTimeout, Usage: "how long to wait for the guest to shut down before forcing it", Value: 30 * time.Second},
+			&cli.BoolFlag{Name: define.FlagForce, Usage: "stop the VM immediately without waiting for the guest to shut down"},
+		},
+		Action: func(ctx context.Context, command *cli.Command) error {
+			outcome, err := revm.Stop(ctx, command.String(define.FlagSessionID), revm.StopOptions{
+				Timeout: command.Duration(define.FlagTimeout),
+				Force:   command.Bool(define.FlagForce),
+			})
+			if err != nil {
+				return err
+			}
+			if outcome != "" {
+				fmt.Fprintf(os.Stdout, "stopped: %s\n", outcome)
+			}
+			return nil
+		},
+	}
+}
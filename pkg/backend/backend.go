@@ -2,12 +2,25 @@ package backend
 
 import (
 	"context"
+	"errors"
 )
 
+// ErrForceStopped is returned by Start when ForceStop ended the VM.
+var ErrForceStopped = errors.New("virtual machine force stopped")
+
 type Backend interface {
 	// vmWaitAbortCtx only aborts the host-side wait for the VM to exit. It must
 	// not be used as the graceful guest shutdown request path.
 	Start(vmWaitAbortCtx context.Context) error
+	// RequestShutdown asks the guest to shut down. A hung guest may never do.
 	RequestShutdown(ctx context.Context) error
+	// ForceStop ends the VM without involving the guest: Start returns
+	// ErrForceStopped at once and the VMM is terminated even if the guest is
+	// hung.
 	ForceStop(ctx context.Context) error
+	// Release is called once the host has torn down after the VM ended, and
+	// before the workspace lock is dropped. If ForceStop could not end the
+	// VMM, Release does not return: a backend whose VMM only goes away with
+	// the process exits it here. It is idempotent.
+	Release()
 }
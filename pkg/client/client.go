@@ -0,0 +1,303 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+// Package client is a typed client for the management API that a running
+// session serves on its vmctl socket. Requests and responses use the same
+// types as management.Server, see OpenAPI for the full contract.
+package client
+
+//go:generate go run gen_openapi.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"linuxvm/pkg/network"
+	"linuxvm/pkg/protocol"
+	"linuxvm/pkg/service/management"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// maxEventSize bounds one server-sent event; an exec output chunk is at most
+// one SSH channel write, base64 encoded.
+const maxEventSize = 4 << 20
+
+// Client talks to the management API of one session. It is safe for
+// concurrent use.
+type Client struct {
+	api *network.Client
+	// stream has no request timeout, streaming calls are bounded by ctx only.
+	stream *network.Client
+}
+
+// New returns a client for the management API listening on socketPath,
+// usually the vmctl socket in the session workspace. opts apply to
+// request/response calls; streaming calls such as Exec ignore the timeout.
+func New(socketPath string, opts ...network.ClientOption) *Client {
+	return &Client{
+		api:    network.NewUnixClient(socketPath, opts...),
+		stream: network.NewUnixClient(socketPath, append(opts, network.WithTimeout(0))...),
+	}
+}
+
+// Close releases idle connections.
+func (c *Client) Close() error {
+	_ = c.api.Close()
+	return c.stream.Close()
+}
+
+// APIError is returned when the management API answers with an unexpected
+// status code.
+type APIError struct {
+	StatusCode int
+	// Message is the error reported by the server, if any.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("management API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("management API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// newAPIError decodes a management.ErrorResponse body, falling back to the
+// plain text body that some handlers write.
+func newAPIError(status int, body []byte) error {
+	var resp management.ErrorResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		return &APIError{StatusCode: status, Message: resp.Error}
+	}
+	return &APIError{StatusCode: status, Message: strings.TrimSpace(string(body))}
+}
+
+// getJSON sends req and decodes a 200 response into out.
+func getJSON(ctx context.Context, req *network.Request, out any) error {
+	body, status, err := req.Header("Accept", "application/json").DoAndRead(ctx)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return newAPIError(status, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// Health returns the readiness of the VM components. A VM that is not ready
+// yet is not an error: check HealthView.Ready. With wait > 0 the server holds
+// the request until the VM is ready or wait has passed.
+func (c *Client) Health(ctx context.Context, wait time.Duration) (management.HealthView, error) {
+	api := c.api
+	req := api.Get(management.PathHealth)
+	if wait > 0 {
+		api = c.stream
+		req = api.Get(management.PathHealth).Query("wait", wait.String())
+	}
+	body, status, err := req.Header("Accept", "application/json").DoAndRead(ctx)
+	if err != nil {
+		return management.HealthView{}, fmt.Errorf("health check: %w", err)
+	}
+	if status != http.StatusOK && status != http.StatusServiceUnavailable {
+		return management.HealthView{}, fmt.Errorf("health check: %w", newAPIError(status, body))
+	}
+
+	var view management.HealthView
+	if err := json.Unmarshal(body, &view); err != nil {
+		return management.HealthView{}, fmt.Errorf("decode health: %w", err)
+	}
+	return view, nil
+}
+
+// VMConfig returns the configuration of the running VM.
+func (c *Client) VMConfig(ctx context.Context) (management.VMConfigView, error) {
+	var view management.VMConfigView
+	if err := getJSON(ctx, c.api.Get(management.PathVMConfig), &view); err != nil {
+		return management.VMConfigView{}, fmt.Errorf("fetch vmconfig: %w", err)
+	}
+	return view, nil
+}
+
+// Version describes the build of the host. Hosts that predate /v2/version
+// are reported as protocol.LegacyVersionInfo.
+func (c *Client) Version(ctx context.Context) (protocol.VersionInfo, error) {
+	var info protocol.VersionInfo
+	err := getJSON(ctx, c.api.Get(management.PathVersion), &info)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return protocol.LegacyVersionInfo(), nil
+	}
+	if err != nil {
+		return protocol.VersionInfo{}, fmt.Errorf("fetch version: %w", err)
+	}
+	return info, nil
+}
+
+// Attach returns the connection data needed to reach the guest over SSH, in
+// the newest schema version supported by both the host and this client.
+func (c *Client) Attach(ctx context.Context) (protocol.AttachSpec, error) {
+	info, err := c.Version(ctx)
+	if err != nil {
+		return protocol.AttachSpec{}, err
+	}
+	version, err := protocol.AttachSpecRange.Negotiate(info.Schemas.AttachSpec)
+	if err != nil {
+		return protocol.AttachSpec{}, fmt.Errorf("negotiate attach spec version: %w", err)
+	}
+
+	var spec protocol.AttachSpec
+	req := c.api.Get(management.PathAttach).Query(protocol.SchemaVersionQuery, strconv.Itoa(version))
+	if err := getJSON(ctx, req, &spec); err != nil {
+		return protocol.AttachSpec{}, fmt.Errorf("fetch attach spec: %w", err)
+	}
+	if !protocol.AttachSpecRange.Contains(spec.SchemaVersion) {
+		return protocol.AttachSpec{}, fmt.Errorf("host returned attach spec version %d, supported %d-%d",
+			spec.SchemaVersion, protocol.AttachSpecRange.Min, protocol.AttachSpecRange.Max)
+	}
+	return spec, nil
+}
+
+// EventMetrics returns the delivery metrics of every event reporter of the
+// session.
+func (c *Client) EventMetrics(ctx context.Context) ([]management.EventReporterMetrics, error) {
+	var metrics []management.EventReporterMetrics
+	if err := getJSON(ctx, c.api.Get(management.PathEventMetrics), &metrics); err != nil {
+		return nil, fmt.Errorf("fetch event metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// StopOptions controls Stop.
+type StopOptions struct {
+	// Force stops the VM immediately instead of asking the guest to shut down.
+	Force bool
+	// Timeout makes the host force the stop when the guest has not shut down
+	// in time; zero leaves the guest as long as it takes.
+	Timeout time.Duration
+	// Wait holds the call until the VM has ended.
+	Wait bool
+}
+
+// Stop requests the VM to stop. Without opts.Wait it returns once the request
+// is accepted, with a result that is usually not Stopped yet.
+//
+// libkrun exits the launcher process as soon as the guest powers off, which
+// can happen before the host answers a waiting request. Stop reports such a
+// connection closed without answer as a VM that ended gracefully, or forced
+// with opts.Force.
+func (c *Client) Stop(ctx context.Context, opts StopOptions) (management.StopResult, error) {
+	api := c.api
+	if opts.Wait {
+		api = c.stream
+	}
+	req := api.Post(management.PathStop)
+	if opts.Force {
+		req.Query("force", strconv.FormatBool(true))
+	}
+	if opts.Timeout > 0 {
+		req.Query("timeout", opts.Timeout.String())
+	}
+	if opts.Wait {
+		req.Query("wait", strconv.FormatBool(true))
+	}
+
+	body, status, err := req.Header("Accept", "application/json").DoAndRead(ctx)
+	if err != nil {
+		if opts.Wait && ctx.Err() == nil && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			outcome := management.StopGraceful
+			if opts.Force {
+				outcome = management.StopForced
+			}
+			return management.StopResult{Stopped: true, Outcome: outcome}, nil
+		}
+		return management.StopResult{}, fmt.Errorf("request stop: %w", err)
+	}
+	if status != http.StatusOK {
+		return management.StopResult{}, fmt.Errorf("request stop: %w", newAPIError(status, body))
+	}
+
+	// Hosts before StopResult answer null, which leaves result zero.
+	var result management.StopResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return management.StopResult{}, fmt.Errorf("decode stop result: %w", err)
+	}
+	return result, nil
+}
+
+// Exec runs a command in the guest and copies its output to stdout and
+// stderr, either of which may be nil to discard it. The returned exit
+// describes how the command ended; err is only set when the stream itself
+// failed. req.Version is always set to protocol.ExecProtocolVersion.
+func (c *Client) Exec(ctx context.Context, req protocol.ExecRequest, stdout, stderr io.Writer) (protocol.ExecExit, error) {
+	req.Version = protocol.ExecProtocolVersion
+	data, err := json.Marshal(req)
+	if err != nil {
+		return protocol.ExecExit{}, fmt.Errorf("encode exec request: %w", err)
+	}
+
+	resp, err := c.stream.Post(management.PathExec).JSON().
+		Header("Accept", "text/event-stream").
+		Body(bytes.NewReader(data)).
+		Do(ctx)
+	if err != nil {
+		return protocol.ExecExit{}, fmt.Errorf("exec: %w", err)
+	}
+	defer network.CloseResponse(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return protocol.ExecExit{}, fmt.Errorf("exec: %w", newAPIError(resp.StatusCode, body))
+	}
+
+	exit, err := decodeExecEvents(resp.Body, stdout, stderr)
+	if err != nil {
+		return protocol.ExecExit{}, fmt.Errorf("exec: %w", err)
+	}
+	return exit, nil
+}
+
+var errExecNoExit = errors.New("stream ended without exit event")
+
+// decodeExecEvents reads a protocol.ExecProtocolVersion event stream until
+// its exit event.
+func decodeExecEvents(r io.Reader, stdout, stderr io.Writer) (protocol.ExecExit, error) {
+	for event, err := range sse.Read(r, &sse.ReadConfig{MaxEventSize: maxEventSize}) {
+		if err != nil {
+			return protocol.ExecExit{}, fmt.Errorf("read event: %w", err)
+		}
+
+		switch event.Type {
+		case protocol.ExecEventStdout, protocol.ExecEventStderr:
+			w := stdout
+			if event.Type == protocol.ExecEventStderr {
+				w = stderr
+			}
+			if w == nil {
+				continue
+			}
+			var out protocol.ExecOutput
+			if err := json.Unmarshal([]byte(event.Data), &out); err != nil {
+				return protocol.ExecExit{}, fmt.Errorf("decode %s event: %w", event.Type, err)
+			}
+			if _, err := w.Write(out.Data); err != nil {
+				return protocol.ExecExit{}, fmt.Errorf("write %s: %w", event.Type, err)
+			}
+		case protocol.ExecEventExit:
+			var exit protocol.ExecExit
+			if err := json.Unmarshal([]byte(event.Data), &exit); err != nil {
+				return protocol.ExecExit{}, fmt.Errorf("decode exit event: %w", err)
+			}
+			return exit, nil
+		}
+	}
+	return protocol.ExecExit{}, errExecNoExit
+}
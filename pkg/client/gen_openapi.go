@@ -0,0 +1,21 @@
+//go:build ignore
+
+// gen_openapi writes openapi.json from the types of the management API. Run
+// it with go generate in this directory.
+package main
+
+import (
+	"linuxvm/pkg/client"
+	"log"
+	"os"
+)
+
+func main() {
+	doc, err := client.OpenAPI()
+	if err != nil {
+		log.Fatalf("generate OpenAPI document: %v", err)
+	}
+	if err := os.WriteFile("openapi.json", append(doc, '\n'), 0644); err != nil {
+		log.Fatalf("write openapi.json: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package client
+
+import (
+	"encoding/json"
+	"linuxvm/pkg/protocol"
+	"linuxvm/pkg/service/management"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the OpenAPI version of the document returned by OpenAPI.
+const OpenAPIVersion = "3.0.3"
+
+const (
+	contentJSON   = "application/json"
+	contentSSE    = "text/event-stream"
+	contentTar    = "application/x-tar"
+	schemaRefBase = "#/components/schemas/"
+)
+
+type apiParam struct {
+	name        string
+	schemaType  string
+	description string
+}
+
+// apiBody is a request or response body. Streams list the event types and
+// the type of their data in events.
+type apiBody struct {
+	contentType string
+	value       any
+	description string
+	events      map[string]any
+}
+
+type apiOperation struct {
+	method   string
+	path     string
+	summary  string
+	query    []apiParam
+	request  *apiBody
+	status   int
+	response *apiBody
+	// alternative is another content type of response.
+	alternative *apiBody
+	// notReady adds a 503 answer with the same body as response.
+	notReady bool
+}
+
+// apiOperations describes every route that management.Server registers,
+// using the types the handlers decode and encode.
+var apiOperations = []apiOperation{
+	{method: http.MethodGet, path: management.PathVersion, summary: "Describe the build and the supported schema versions and features", status: http.StatusOK,
+		response: &apiBody{contentType: contentJSON, value: protocol.VersionInfo{}}},
+	{method: http.MethodGet, path: management.PathHealth, summary: "Report the readiness of the VM components", status: http.StatusOK,
+		query:    []apiParam{{name: "wait", schemaType: "string", description: "Go duration to hold the request until the VM is ready."}},
+		response: &apiBody{contentType: contentJSON, value: management.HealthView{}, description: "Every component is ready."},
+		notReady: true},
+	{method: http.MethodGet, path: management.PathVMConfig, summary: "Return the configuration of the running VM", status: http.StatusOK,
+		response: &apiBody{contentType: contentJSON, value: management.VMConfigView{}}},
+	{method: http.MethodGet, path: management.PathAttach, summary: "Return the data needed to reach the guest over SSH", status: http.StatusOK,
+		query:    []apiParam{{name: protocol.SchemaVersionQuery, schemaType: "integer", description: "Attach spec version to return, default the newest; see /v2/version."}},
+		response: &apiBody{contentType: contentJSON, value: protocol.AttachSpec{}}},
+	{method: http.MethodPost, path: management.PathExec, summary: "Run a command in the guest and stream its output", status: http.StatusOK,
+		request: &apiBody{contentType: contentJSON, value: protocol.ExecRequest{}},
+		response: &apiBody{contentType: contentSSE, description: "Output events followed by a single exit event.", events: map[string]any{
+			protocol.ExecEventStdout: protocol.ExecOutput{},
+			protocol.ExecEventStderr: protocol.ExecOutput{},
+			protocol.ExecEventExit:   protocol.ExecExit{},
+		}}},
+	{method: http.MethodPost, path: management.PathExecInteractive, summary: "Run a command in the guest over an upgraded connection", status: http.StatusSwitchingProtocols,
+		request:  &apiBody{contentType: contentJSON, value: protocol.InteractiveExecRequest{}},
+		response: &apiBody{description: "The connection switches to the " + protocol.ExecStreamUpgrade + " frame protocol."}},
+	{method: http.MethodGet, path: management.PathStats, summary: "Return guest resource usage", status: http.StatusOK,
+		query: []apiParam{
+			{name: "stream", schemaType: "boolean", description: "Keep sending samples as stats events."},
+			{name: "interval", schemaType: "string", description: "Go duration between streamed samples, default 2s."},
+		},
+		response:    &apiBody{contentType: contentJSON, value: protocol.GuestStats{}},
+		alternative: &apiBody{contentType: contentSSE, events: map[string]any{"stats": protocol.GuestStats{}}}},
+	{method: http.MethodGet, path: management.PathCopy, summary: "Download a guest path as a tar archive", status: http.StatusOK,
+		query:    []apiParam{{name: "path", schemaType: "string", description: "Absolute guest path."}},
+		response: &apiBody{contentType: contentTar}},
+	{method: http.MethodPut, path: management.PathCopy, summary: "Extract a tar archive into a guest directory", status: http.StatusOK,
+		query:   []apiParam{{name: "path", schemaType: "string", description: "Absolute guest directory."}},
+		request: &apiBody{contentType: contentTar}},
+	{method: http.MethodGet, path: management.PathPorts, summary: "List runtime port forwards", status: http.StatusOK,
+		response: &apiBody{contentType: contentJSON, value: []protocol.PortForward{}}},
+	{method: http.MethodPost, path: management.PathPorts, summary: "Expose a guest port on the host", status: http.StatusCreated,
+		request:  &apiBody{contentType: contentJSON, value: protocol.PortForward{}},
+		response: &apiBody{contentType: contentJSON, value: protocol.PortForward{}}},
+	{method: http.MethodDelete, path: management.PathPorts, summary: "Remove a runtime port forward", status: http.StatusOK,
+		query: []apiParam{
+			{name: "host", schemaType: "string", description: "Host address of the forward."},
+			{name: "protocol", schemaType: "string", description: "tcp (default), udp or unix."},
+		}},
+	{method: http.MethodPost, path: management.PathStop, summary: "Stop the VM", status: http.StatusOK,
+		query: []apiParam{
+			{name: "force", schemaType: "boolean", description: "Stop the VMM immediately without involving the guest."},
+			{name: "timeout", schemaType: "string", description: "Go duration after which a graceful stop is forced."},
+			{name: "wait", schemaType: "boolean", description: "Hold the request until the VM has ended."},
+		},
+		response: &apiBody{contentType: contentJSON, value: management.StopResult{}}},
+	{method: http.MethodGet, path: management.PathEventMetrics, summary: "Return the delivery metrics of every event reporter", status: http.StatusOK,
+		response: &apiBody{contentType: contentJSON, value: []management.EventReporterMetrics{}}},
+	{method: http.MethodGet, path: management.PathEvents, summary: "Stream lifecycle events", status: http.StatusOK,
+		response: &apiBody{contentType: contentSSE, description: "One event per lifecycle change, typed by its kind; the data is the JSON encoded event. " +
+			"Events since the start of the session are replayed first, or those after the Last-Event-ID request header."}},
+}
+
+// OpenAPI returns the OpenAPI document of the management API. Schemas are
+// derived from the Go types shared by management.Server and Client, so the
+// document cannot drift from the implementation.
+func OpenAPI() ([]byte, error) {
+	gen := &schemaGenerator{schemas: map[string]any{}}
+	paths := map[string]map[string]any{}
+
+	for _, op := range apiOperations {
+		operation := map[string]any{
+			"summary":     op.summary,
+			"operationId": operationID(op),
+		}
+		if len(op.query) > 0 {
+			params := make([]any, 0, len(op.query))
+			for _, p := range op.query {
+				params = append(params, map[string]any{
+					"name":        p.name,
+					"in":          "query",
+					"description": p.description,
+					"schema":      map[string]any{"type": p.schemaType},
+				})
+			}
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  gen.content(op.request),
+			}
+		}
+
+		success := map[string]any{"description": http.StatusText(op.status)}
+		if op.response != nil {
+			if op.response.description != "" {
+				success["description"] = op.response.description
+			}
+			if op.response.contentType != "" {
+				content := gen.content(op.response)
+				if op.alternative != nil {
+					for k, v := range gen.content(op.alternative) {
+						content[k] = v
+					}
+				}
+				success["content"] = content
+			}
+		}
+		responses := map[string]any{
+			strconv.Itoa(op.status): success,
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					contentJSON: map[string]any{"schema": gen.schema(reflect.TypeOf(management.ErrorResponse{}))},
+				},
+			},
+		}
+		if op.notReady {
+			responses[strconv.Itoa(http.StatusServiceUnavailable)] = map[string]any{
+				"description": "Not every component is ready.",
+				"content":     success["content"],
+			}
+		}
+		operation["responses"] = responses
+
+		if paths[op.path] == nil {
+			paths[op.path] = map[string]any{}
+		}
+		paths[op.path][strings.ToLower(op.method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": OpenAPIVersion,
+		"info": map[string]any{
+			"title":       "revm management API",
+			"description": "Served over HTTP on the vmctl unix socket of a running session.",
+			"version":     "v2",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": gen.schemas},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func operationID(op apiOperation) string {
+	name := strings.TrimPrefix(op.path, "/v2/")
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method))
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '_' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+type schemaGenerator struct {
+	schemas map[string]any
+}
+
+func (g *schemaGenerator) content(body *apiBody) map[string]any {
+	media := map[string]any{}
+	switch {
+	case body.value != nil:
+		media["schema"] = g.schema(reflect.TypeOf(body.value))
+	case body.contentType == contentJSON:
+		media["schema"] = map[string]any{}
+	default:
+		media["schema"] = map[string]any{"type": "string", "format": "binary"}
+	}
+	if len(body.events) > 0 {
+		// OpenAPI has no notion of event streams; list the data schema of
+		// every event type as an extension.
+		names := make([]string, 0, len(body.events))
+		for name := range body.events {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		events := map[string]any{}
+		for _, name := range names {
+			events[name] = g.schema(reflect.TypeOf(body.events[name]))
+		}
+		media["schema"] = map[string]any{"type": "string"}
+		media["x-events"] = events
+	}
+	return map[string]any{body.contentType: media}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the JSON schema of t following encoding/json rules. Named
+// structs are added to the components and referenced.
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate.
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return map[string]any{"$ref": schemaRefBase + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	g.fields(t, properties, &required)
+
+	obj := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		obj["required"] = required
+	}
+	return obj
+}
+
+// fields adds the JSON fields of t, including those promoted from embedded
+// structs.
+func (g *schemaGenerator) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaName qualifies the type name with its package so that equally named
+// types of management and protocol do not collide.
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+}
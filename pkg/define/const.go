@@ -53,6 +53,15 @@ const (
 
 const (
 	RestAPIVMConfigURL = "/vmconfig"
+	// RestAPIVersionURL returns the protocol.VersionInfo of the host, which
+	// the guest agent negotiates the guest spec version against.
+	RestAPIVersionURL = "/version"
+	// RestAPIMountsReadyURL is posted by the guest agent once all block
+	// devices and virtiofs shares are mounted.
+	RestAPIMountsReadyURL = "/mounts-ready"
+	// RestAPIGuestEventsURL receives the protocol.GuestEvent posted by the
+	// guest agent.
+	RestAPIGuestEventsURL = "/events"
 )
 
 const (
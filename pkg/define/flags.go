@@ -20,6 +20,20 @@ const (
 	FlagManageAPIFile           = "manage-api"
 	FlagExportSSHKeyPrivateFile = "ssh-key"
 	FlagReportEvents            = "report-events"
+	FlagJSON                    = "json"
+	FlagIncludeDisks            = "include-disks"
+	FlagOlderThan               = "older-than"
+	FlagDryRun                  = "dry-run"
+	FlagConfig                  = "config"
+	FlagTimeout                 = "timeout"
+	FlagForce                   = "force"
+	FlagDetach                  = "detach"
+	FlagFollow                  = "follow"
+	FlagSince                   = "since"
+	FlagLogSource               = "source"
+	FlagPublish                 = "publish"
+	FlagAllowUID                = "allow-uid"
+	FlagAllowGID                = "allow-gid"
 
 	ContainerDiskUUID = "162cf68f-93c7-49ad-be53-45ed0e9fe42b"
 
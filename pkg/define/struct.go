@@ -18,6 +18,8 @@ type MachineSpec struct {
 	GVPNotifyAddr string `json:"GVPNotifyAddr,omitempty"`
 
 	VirtualNetworkMode VNetMode `json:"virtualNetworkMode,omitempty"`
+	// PortForwards are published by gvproxy from boot; gvisor mode only.
+	PortForwards []PortMapping `json:"portForwards,omitempty"`
 
 	LogFile           string            `json:"logFile,omitempty"`
 	Mounts            []Mount           `json:"mounts,omitempty"`
@@ -54,6 +56,14 @@ type Mount struct {
 	UUID     string `json:"uuid"`
 }
 
+// PortMapping publishes a guest port on a host address.
+type PortMapping struct {
+	HostIP    string `json:"hostIP"`
+	HostPort  uint16 `json:"hostPort"`
+	GuestPort uint16 `json:"guestPort"`
+	Protocol  string `json:"protocol"` // "tcp" or "udp"
+}
+
 type SSHInfo struct {
 	// HOST
 	HostSSHPrivateKeyFile  string `json:"hostSSHKeyFile,omitempty"`
@@ -4,4 +4,7 @@ var (
 	Version   string
 	CommitID  string
 	BuildDate string
+	// AssetsRelease is the revm-assets release that libkrun, libkrunfw and
+	// the built-in rootfs come from.
+	AssetsRelease string
 )
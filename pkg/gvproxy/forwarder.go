@@ -0,0 +1,69 @@
+package gvproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"linuxvm/pkg/network"
+	"net/http"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+)
+
+// ForwarderClient manages the port forwards of a running gvproxy through the
+// /services/forwarder API on its control socket.
+type ForwarderClient struct {
+	client *network.Client
+}
+
+func NewForwarderClient(controlAddr string) (*ForwarderClient, error) {
+	path, err := parseUnixSocketPath(controlAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gvproxy control address: %w", err)
+	}
+	return &ForwarderClient{client: network.NewUnixClient(path)}, nil
+}
+
+func (c *ForwarderClient) Close() error {
+	return c.client.Close()
+}
+
+// All returns the active forwards, including the static SSH forward.
+func (c *ForwarderClient) All(ctx context.Context) ([]types.ExposeRequest, error) {
+	body, status, err := c.client.Get("/services/forwarder/all").DoAndRead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list forwards: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("list forwards: gvproxy returned status %d: %s", status, bytes.TrimSpace(body))
+	}
+	var forwards []types.ExposeRequest
+	if err := json.Unmarshal(body, &forwards); err != nil {
+		return nil, fmt.Errorf("decode forwards: %w", err)
+	}
+	return forwards, nil
+}
+
+func (c *ForwarderClient) Expose(ctx context.Context, req types.ExposeRequest) error {
+	return c.post(ctx, "/services/forwarder/expose", req)
+}
+
+func (c *ForwarderClient) Unexpose(ctx context.Context, req types.UnexposeRequest) error {
+	return c.post(ctx, "/services/forwarder/unexpose", req)
+}
+
+func (c *ForwarderClient) post(ctx context.Context, path string, req any) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	body, status, err := c.client.Post(path).JSON().Body(bytes.NewReader(data)).DoAndRead(ctx)
+	if err != nil {
+		return fmt.Errorf("gvproxy %s: %w", path, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("gvproxy %s: %s", path, bytes.TrimSpace(body))
+	}
+	return nil
+}
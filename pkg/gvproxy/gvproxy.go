@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"linuxvm/pkg/define"
 	"net"
 	"net/http"
 	"net/url"
@@ -42,6 +43,7 @@ type Spec struct {
 	GuestSSHListenAddr  string
 	GuestIP             string
 	HostLoopbackAddress string
+	PortForwards        []define.PortMapping
 }
 
 func NewConfig(spec Spec) (*Config, error) {
@@ -83,6 +85,10 @@ func NewConfig(spec Spec) (*Config, error) {
 	sshServerGuestAddr := net.JoinHostPort(spec.GuestIP, sshPortStr)
 
 	logrus.Infof("configuring local port forwarding from %s to %s", spec.HostSSHForwardAddr, sshServerGuestAddr)
+	forwards, err := stackForwards(spec, sshServerGuestAddr)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Config{
 		ControlAddr: spec.ControlAddr,
@@ -100,9 +106,7 @@ func NewConfig(spec Spec) (*Config, error) {
 				internalZone("docker.internal."),
 				internalZone("revm.internal."),
 			},
-			Forwards: map[string]string{
-				spec.HostSSHForwardAddr: sshServerGuestAddr,
-			},
+			Forwards: forwards,
 			NAT: map[string]string{
 				hostIP: spec.HostLoopbackAddress,
 			},
@@ -0,0 +1,61 @@
+package gvproxy
+
+import (
+	"fmt"
+	"linuxvm/pkg/define"
+	"net"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// udpForwardPrefix marks a UDP entry in types.Configuration.Forwards.
+const udpForwardPrefix = "udp:"
+
+// ValidatePortForwards rejects published ports that would bind the host
+// address of the SSH forward.
+func ValidatePortForwards(hostSSHForwardAddr string, forwards []define.PortMapping) error {
+	sshHost, sshPortStr, err := net.SplitHostPort(hostSSHForwardAddr)
+	if err != nil {
+		return fmt.Errorf("invalid ssh forward address %q: %w", hostSSHForwardAddr, err)
+	}
+	sshPort, err := strconv.ParseUint(sshPortStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid ssh forward address %q: %w", hostSSHForwardAddr, err)
+	}
+	sshIP := net.ParseIP(sshHost)
+
+	for _, f := range forwards {
+		if f.Protocol == "udp" || uint64(f.HostPort) != sshPort {
+			continue
+		}
+		ip := net.ParseIP(f.HostIP)
+		if ip.IsUnspecified() || sshIP.IsUnspecified() || ip.Equal(sshIP) {
+			return fmt.Errorf("published port %s collides with the SSH forward on %s",
+				net.JoinHostPort(f.HostIP, strconv.Itoa(int(f.HostPort))), hostSSHForwardAddr)
+		}
+	}
+	return nil
+}
+
+// stackForwards returns the gvproxy forward table: the SSH forward plus the
+// published ports, UDP ones keyed with the "udp:" prefix.
+func stackForwards(spec Spec, sshServerGuestAddr string) (map[string]string, error) {
+	if err := ValidatePortForwards(spec.HostSSHForwardAddr, spec.PortForwards); err != nil {
+		return nil, err
+	}
+
+	forwards := map[string]string{
+		spec.HostSSHForwardAddr: sshServerGuestAddr,
+	}
+	for _, f := range spec.PortForwards {
+		local := net.JoinHostPort(f.HostIP, strconv.Itoa(int(f.HostPort)))
+		if f.Protocol == "udp" {
+			local = udpForwardPrefix + local
+		}
+		remote := net.JoinHostPort(spec.GuestIP, strconv.Itoa(int(f.GuestPort)))
+		logrus.Infof("publishing %s/%s to guest %s", local, f.Protocol, remote)
+		forwards[local] = remote
+	}
+	return forwards, nil
+}
@@ -16,24 +16,52 @@ import (
 )
 
 // TunnelHostUnixToGuest creates a Unix socket tunnel that forwards connections to a guest VM.
-func TunnelHostUnixToGuest(ctx context.Context, gvproxyCtlUnixAddr, listenUnixAddr, targetIP string, targetPort uint16) error {
+// Connections from local users not admitted by peers are rejected.
+func TunnelHostUnixToGuest(ctx context.Context, gvproxyCtlUnixAddr, listenUnixAddr, targetIP string, targetPort uint16, peers network.PeerAllowlist) error {
+	t, err := ListenUnixTunnel(gvproxyCtlUnixAddr, listenUnixAddr, targetIP, targetPort, peers)
+	if err != nil {
+		return err
+	}
+	return t.Serve(ctx)
+}
+
+// UnixTunnel is a listening Unix socket tunnel to a guest port.
+type UnixTunnel struct {
+	ln          net.Listener
+	gvproxyPath string
+	targetIP    string
+	targetPort  uint16
+}
+
+// ListenUnixTunnel listens on listenUnixAddr for a tunnel to targetIP:targetPort,
+// so that the caller learns about a bad socket path before serving it.
+func ListenUnixTunnel(gvproxyCtlUnixAddr, listenUnixAddr, targetIP string, targetPort uint16, peers network.PeerAllowlist) (*UnixTunnel, error) {
 	gvproxyPath, err := parseUnixSocketPath(gvproxyCtlUnixAddr)
 	if err != nil {
-		return fmt.Errorf("invalid gvproxy socket address: %w", err)
+		return nil, fmt.Errorf("invalid gvproxy socket address: %w", err)
 	}
 
 	listenPath, err := parseUnixSocketPath(listenUnixAddr)
 	if err != nil {
-		return fmt.Errorf("invalid listen socket address: %w", err)
+		return nil, fmt.Errorf("invalid listen socket address: %w", err)
 	}
 
 	ln, err := createUnixListenerSockFile(listenPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &UnixTunnel{
+		ln:          peers.Listener(ln, "unix tunnel"),
+		gvproxyPath: gvproxyPath,
+		targetIP:    targetIP,
+		targetPort:  targetPort,
+	}, nil
+}
 
+// Serve forwards connections until ctx is done, then closes the listener.
+func (t *UnixTunnel) Serve(ctx context.Context) error {
 	var closeOnce sync.Once
-	closeLn := func() { closeOnce.Do(func() { _ = ln.Close() }) }
+	closeLn := func() { closeOnce.Do(func() { _ = t.ln.Close() }) }
 	defer closeLn()
 
 	go func() {
@@ -41,7 +69,7 @@ func TunnelHostUnixToGuest(ctx context.Context, gvproxyCtlUnixAddr, listenUnixAd
 		closeLn()
 	}()
 
-	return acceptLoop(ctx, ln, gvproxyPath, targetIP, targetPort)
+	return acceptLoop(ctx, t.ln, t.gvproxyPath, t.targetIP, t.targetPort)
 }
 
 func parseUnixSocketPath(addr string) (string, error) {
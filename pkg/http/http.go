@@ -23,6 +23,8 @@ type Server struct {
 	server      *http.Server
 	Mux         *http.ServeMux
 	OnListening func() // called once after net.Listen succeeds
+	// Peers, when set, rejects connections from local users it does not admit.
+	Peers *network.PeerAllowlist
 }
 
 func NewUnixSockHTTPServer(name, listener string) *Server {
@@ -48,6 +50,10 @@ func (s *Server) Serve(ctx context.Context) error {
 	}
 	defer os.Remove(addr.Path)
 
+	if s.Peers != nil {
+		ln = s.Peers.Listener(ln, s.name)
+	}
+
 	if s.OnListening != nil {
 		s.OnListening()
 	}
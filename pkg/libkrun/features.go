@@ -0,0 +1,47 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package libkrun
+
+/*
+#include <libkrun.h>
+*/
+import "C"
+
+// features names the optional libkrun features reported by Features.
+var features = []struct {
+	name string
+	id   C.uint64_t
+}{
+	{"net", C.KRUN_FEATURE_NET},
+	{"blk", C.KRUN_FEATURE_BLK},
+	{"gpu", C.KRUN_FEATURE_GPU},
+	{"snd", C.KRUN_FEATURE_SND},
+	{"input", C.KRUN_FEATURE_INPUT},
+	{"tee", C.KRUN_FEATURE_TEE},
+	{"amd-sev", C.KRUN_FEATURE_AMD_SEV},
+	{"intel-tdx", C.KRUN_FEATURE_INTEL_TDX},
+	{"aws-nitro", C.KRUN_FEATURE_AWS_NITRO},
+	{"virgl-resource-map2", C.KRUN_FEATURE_VIRGL_RESOURCE_MAP2},
+}
+
+// Features returns the optional features the linked libkrun was built with.
+// Features unknown to an older library are left out.
+func Features() []string {
+	var supported []string
+	for _, f := range features {
+		if C.krun_has_feature(f.id) == 1 {
+			supported = append(supported, f.name)
+		}
+	}
+	return supported
+}
+
+// MaxVCPUs returns the number of vCPUs the hypervisor supports, or 0 if it
+// cannot tell.
+func MaxVCPUs() int {
+	n := int(C.krun_get_max_vcpus())
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -4,17 +4,42 @@ package libkrun
 
 import (
 	"context"
+	"linuxvm/pkg/backend"
 	"linuxvm/pkg/define"
+	"os"
 	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
+// forceExitTimeout is how long the host gets to tear down its services after
+// a force stop before the process, and with it the VMM, is terminated.
+const forceExitTimeout = 10 * time.Second
+
+// forceExitCode is the exit status of a process terminated by ForceStop, as
+// if it had been killed with SIGKILL.
+const forceExitCode = 128 + 9
+
 type Provider struct {
 	mc      *define.MachineSpec
 	libkrun *Libkrun
+
+	forced    chan struct{}
+	forceOnce sync.Once
+
+	mu sync.Mutex
+	// running is set while the libkrun Start call has not returned, that is
+	// while the VMM threads may still be alive.
+	running bool
+	// forceExit terminates the process if the host has not released the
+	// provider forceExitTimeout after ForceStop.
+	forceExit *time.Timer
 }
 
 func NewProvider(mc *define.MachineSpec) *Provider {
-	return &Provider{mc: mc, libkrun: New(mc)}
+	return &Provider{mc: mc, libkrun: New(mc), forced: make(chan struct{})}
 }
 
 func (p *Provider) Create(ctx context.Context) error {
@@ -23,16 +48,21 @@ func (p *Provider) Create(ctx context.Context) error {
 
 func (p *Provider) Start(vmWaitAbortCtx context.Context) error {
 	ch := make(chan error, 1)
+	p.setRunning(true)
 	go func() {
 		runtime.LockOSThread()
 		// vmWaitAbortCtx follows the blocking libkrun Start call. Graceful shutdown is
 		// requested separately through RequestShutdown, not by cancelling this ctx.
-		ch <- p.libkrun.Start(vmWaitAbortCtx)
+		err := p.libkrun.Start(vmWaitAbortCtx)
+		p.setRunning(false)
+		ch <- err
 	}()
 
 	select {
 	case err := <-ch:
 		return err
+	case <-p.forced:
+		return backend.ErrForceStopped
 	case <-vmWaitAbortCtx.Done():
 		return vmWaitAbortCtx.Err()
 	}
@@ -42,6 +72,46 @@ func (p *Provider) RequestShutdown(ctx context.Context) error {
 	return p.libkrun.SendSignal(ctx, define.GuestSignalTerminated)
 }
 
-func (p *Provider) ForceStop(ctx context.Context) error {
-	return p.libkrun.SendSignal(ctx, define.GuestSignalTerminated)
+func (p *Provider) setRunning(running bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = running
+	if !running && p.forceExit != nil {
+		// The VMM is gone, there is nothing left to terminate.
+		p.forceExit.Stop()
+	}
+}
+
+// ForceStop ends the VM without the guest. libkrun runs the VMM on threads of
+// this process and offers no call to stop them, so the VM only goes away with
+// the process: Start returns so the host can tear down, and the process exits
+// in Release or, if the host has not called it after forceExitTimeout, here,
+// as libkrun itself does when the guest powers off.
+func (p *Provider) ForceStop(_ context.Context) error {
+	p.forceOnce.Do(func() {
+		close(p.forced)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !p.running {
+			return
+		}
+		p.forceExit = time.AfterFunc(forceExitTimeout, func() {
+			logrus.Errorf("VMM still running %s after force stop, terminating the process", forceExitTimeout)
+			os.Exit(forceExitCode)
+		})
+	})
+	return nil
+}
+
+// Release exits the process if a force stop left the VMM running: the host
+// has torn down, and returning would let the caller drop the workspace lock
+// while the hung guest is still alive.
+func (p *Provider) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running && p.forceExit != nil {
+		logrus.Warn("VMM still running after force stop, terminating the process")
+		os.Exit(forceExitCode)
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/mdlayher/vsock"
@@ -124,6 +125,16 @@ func NewTCPClient(addr string, opts ...ClientOption) *Client {
 	return newClient(baseURL, dialFunc, cfg)
 }
 
+// NewHTTPClient creates a new HTTP client for a plain http:// or https:// base
+// URL such as https://collector.example:8443.
+func NewHTTPClient(baseURL string, opts ...ClientOption) *Client {
+	cfg := defaultConfig()
+	applyOptions(cfg, opts)
+
+	dialer := &net.Dialer{Timeout: cfg.timeout}
+	return newClient(strings.TrimSuffix(baseURL, "/"), dialer.DialContext, cfg)
+}
+
 // NewVSockClient creates a new HTTP client for VSock communication.
 func NewVSockClient(cid, port uint32, opts ...ClientOption) *Client {
 	cfg := defaultConfig()
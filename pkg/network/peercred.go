@@ -0,0 +1,103 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PeerCred identifies the process on the other end of a unix socket.
+type PeerCred struct {
+	UID  uint32
+	GIDs []uint32
+	// PID is 0 when the platform does not report it.
+	PID int32
+}
+
+// UnixPeerCred returns the credentials of the peer of conn, read with
+// SO_PEERCRED on Linux and LOCAL_PEERCRED on macOS.
+func UnixPeerCred(conn *net.UnixConn) (PeerCred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return PeerCred{}, err
+	}
+
+	var cred PeerCred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = peerCred(int(fd))
+	}); err != nil {
+		return PeerCred{}, err
+	}
+	return cred, credErr
+}
+
+// PeerAllowlist restricts which local users may connect to a unix socket. A
+// peer is admitted when it runs as root, as the user of the current process,
+// as one of UIDs, or with one of GIDs. The zero value only admits root and the
+// current user.
+type PeerAllowlist struct {
+	UIDs []uint32 `json:"uids,omitempty"`
+	GIDs []uint32 `json:"gids,omitempty"`
+}
+
+// Allows reports whether cred is admitted.
+func (l PeerAllowlist) Allows(cred PeerCred) bool {
+	if cred.UID == 0 || cred.UID == uint32(os.Getuid()) || slices.Contains(l.UIDs, cred.UID) {
+		return true
+	}
+	for _, gid := range cred.GIDs {
+		if slices.Contains(l.GIDs, gid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener wraps ln so that Accept only returns connections from admitted
+// peers. Rejected connections are logged with name and closed.
+func (l PeerAllowlist) Listener(ln net.Listener, name string) net.Listener {
+	return &peerListener{Listener: ln, allow: l, name: name}
+}
+
+type peerListener struct {
+	net.Listener
+	allow PeerAllowlist
+	name  string
+}
+
+var errPeerRejected = errors.New("peer is not in the allowlist")
+
+func (l *peerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := l.check(conn); err != nil {
+			logrus.Warnf("%s: rejected connection on %q: %v", l.name, l.Addr(), err)
+			_ = conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
+
+func (l *peerListener) check(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("not a unix socket connection: %T", conn)
+	}
+	cred, err := UnixPeerCred(unixConn)
+	if err != nil {
+		return fmt.Errorf("read peer credentials: %w", err)
+	}
+	if !l.allow.Allows(cred) {
+		return fmt.Errorf("%w: uid %d, gids %v, pid %d", errPeerRejected, cred.UID, cred.GIDs, cred.PID)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package network
+
+import "golang.org/x/sys/unix"
+
+func peerCred(fd int) (PeerCred, error) {
+	xucred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return PeerCred{}, err
+	}
+	cred := PeerCred{UID: xucred.Uid}
+	for i := 0; i < int(xucred.Ngroups) && i < len(xucred.Groups); i++ {
+		cred.GIDs = append(cred.GIDs, xucred.Groups[i])
+	}
+	// The pid is informational; older kernels do not report it.
+	if pid, err := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID); err == nil {
+		cred.PID = int32(pid)
+	}
+	return cred, nil
+}
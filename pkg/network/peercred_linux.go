@@ -0,0 +1,51 @@
+package network
+
+import (
+	"errors"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func peerCred(fd int) (PeerCred, error) {
+	ucred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return PeerCred{}, err
+	}
+	cred := PeerCred{UID: ucred.Uid, GIDs: []uint32{ucred.Gid}, PID: ucred.Pid}
+
+	groups, err := peerGroups(fd)
+	switch {
+	case errors.Is(err, unix.ENOPROTOOPT):
+		// Kernels before 4.13 only report the primary group.
+	case err != nil:
+		return PeerCred{}, err
+	}
+	for _, gid := range groups {
+		if !slices.Contains(cred.GIDs, gid) {
+			cred.GIDs = append(cred.GIDs, gid)
+		}
+	}
+	return cred, nil
+}
+
+// peerGroups reads the supplementary groups of the peer with SO_PEERGROUPS,
+// which x/sys/unix has no helper for.
+func peerGroups(fd int) ([]uint32, error) {
+	groups := make([]uint32, 64)
+	for {
+		size := uint32(len(groups) * 4)
+		_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), unix.SOL_SOCKET, unix.SO_PEERGROUPS,
+			uintptr(unsafe.Pointer(&groups[0])), uintptr(unsafe.Pointer(&size)), 0)
+		switch errno {
+		case 0:
+			return groups[:size/4], nil
+		case unix.ERANGE:
+			// size now holds the length the kernel needs.
+			groups = make([]uint32, size/4+1)
+		default:
+			return nil, errno
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package protocol
+
+// ExecProtocolVersion is the newest /v2/exec stream format. Requests that omit
+// the version get the original line-based text stream.
+const ExecProtocolVersion = 2
+
+// Event types of an ExecProtocolVersion stream. Output arrives as any number of
+// stdout and stderr events; exit is always the last event.
+const (
+	ExecEventStdout = "stdout"
+	ExecEventStderr = "stderr"
+	ExecEventExit   = "exit"
+)
+
+// ExecRequest is the body of POST /v2/exec.
+type ExecRequest struct {
+	Version int      `json:"version,omitempty"`
+	Bin     string   `json:"bin,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	WorkDir string   `json:"workdir,omitempty"`
+	User    string   `json:"user,omitempty"`
+	// Timeout is a Go duration string such as "30s"; empty means no limit.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// ExecOutput is the data of stdout and stderr events. Data holds one raw
+// output chunk and is base64 encoded on the wire.
+type ExecOutput struct {
+	Data []byte `json:"data"`
+}
+
+// ExecExit is the data of the exit event.
+type ExecExit struct {
+	// ExitCode is the guest command exit code, 128+N when it was killed by
+	// signal N, or -1 when the command did not run to completion.
+	ExitCode int    `json:"exitCode"`
+	Signal   string `json:"signal,omitempty"`
+	TimedOut bool   `json:"timedOut,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
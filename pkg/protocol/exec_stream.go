@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ExecStreamUpgrade is the Upgrade header value of POST /v2/exec/interactive.
+// After the 101 response both sides exchange exec frames on the connection.
+const ExecStreamUpgrade = "revm-exec"
+
+// Exec frame kinds. Stdin, StdinClose and Resize flow from the client to the
+// server; Stdout, Stderr and Exit flow back. Exit is the last frame.
+const (
+	ExecFrameStdin byte = iota + 1
+	ExecFrameStdinClose
+	ExecFrameResize
+	ExecFrameStdout
+	ExecFrameStderr
+	ExecFrameExit
+)
+
+// MaxExecFramePayload bounds the payload of a single exec frame.
+const MaxExecFramePayload = 1 << 20
+
+// InteractiveExecRequest is the body of POST /v2/exec/interactive. An empty
+// Bin starts the login shell of the user; Version is ignored.
+type InteractiveExecRequest struct {
+	ExecRequest
+	TTY    bool `json:"tty,omitempty"`
+	Width  int  `json:"width,omitempty"`
+	Height int  `json:"height,omitempty"`
+}
+
+// ExecResize is the payload of a resize frame.
+type ExecResize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// WriteExecFrame writes one frame: a kind byte, a big-endian uint32 payload
+// length and the payload.
+func WriteExecFrame(w io.Writer, kind byte, payload []byte) error {
+	if len(payload) > MaxExecFramePayload {
+		return fmt.Errorf("exec frame payload too large: %d bytes", len(payload))
+	}
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadExecFrame reads one frame written by WriteExecFrame.
+func ReadExecFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxExecFramePayload {
+		return 0, nil, fmt.Errorf("exec frame payload too large: %d bytes", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import "time"
+
+// GuestEventKind identifies a milestone that the guest agent reports to the
+// host.
+type GuestEventKind string
+
+const (
+	// GuestEventMountsReady is reported once all block devices and virtiofs
+	// shares are mounted.
+	GuestEventMountsReady GuestEventKind = "guest_mounts_ready"
+	// GuestEventSSHReady is reported once the guest SSH server accepts
+	// connections.
+	GuestEventSSHReady GuestEventKind = "guest_ssh_ready"
+	// GuestEventCommandStarted and GuestEventCommandExited bracket the user
+	// command in rootfs mode.
+	GuestEventCommandStarted GuestEventKind = "guest_command_started"
+	GuestEventCommandExited  GuestEventKind = "guest_command_exited"
+	// GuestEventServiceRestarted is reported each time a supervised guest
+	// service, such as podman, is restarted.
+	GuestEventServiceRestarted GuestEventKind = "guest_service_restarted"
+)
+
+// GuestEventKinds lists every GuestEventKind the host accepts.
+var GuestEventKinds = []GuestEventKind{
+	GuestEventMountsReady,
+	GuestEventSSHReady,
+	GuestEventCommandStarted,
+	GuestEventCommandExited,
+	GuestEventServiceRestarted,
+}
+
+// GuestEvent is posted by the guest agent to the ignition server, which
+// relays it to the host event reporters.
+type GuestEvent struct {
+	Kind    GuestEventKind `json:"kind"`
+	Message string         `json:"message,omitempty"`
+	// Time is the guest clock when the event happened.
+	Time time.Time `json:"time"`
+	// ExitCode is set on GuestEventCommandExited only.
+	ExitCode *int `json:"exitCode,omitempty"`
+}
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// Port forward protocols.
+const (
+	PortProtocolTCP  = "tcp"
+	PortProtocolUDP  = "udp"
+	PortProtocolUnix = "unix"
+)
+
+// PortForward publishes a guest port on the host while the VM runs.
+type PortForward struct {
+	// Protocol is tcp (default), udp, or unix to listen on a host unix socket
+	// and forward its connections to a guest TCP port.
+	Protocol string `json:"protocol,omitempty"`
+	// Host is the host address to listen on: ip:port for tcp and udp, an
+	// absolute socket path for unix.
+	Host string `json:"host"`
+	// GuestPort is the port inside the guest.
+	GuestPort uint16 `json:"guestPort,omitempty"`
+}
+
+// Normalize fills in the default protocol and checks the host address. The
+// guest port is only required to expose, not to unexpose.
+func (p PortForward) Normalize() (PortForward, error) {
+	if p.Protocol == "" {
+		p.Protocol = PortProtocolTCP
+	}
+	switch p.Protocol {
+	case PortProtocolTCP, PortProtocolUDP:
+		host, port, err := net.SplitHostPort(p.Host)
+		if err != nil {
+			return PortForward{}, fmt.Errorf("invalid host address %q: want ip:port", p.Host)
+		}
+		if host != "" && net.ParseIP(host) == nil {
+			return PortForward{}, fmt.Errorf("invalid host address %q: %q is not an IP address", p.Host, host)
+		}
+		if port == "" || port == "0" {
+			return PortForward{}, fmt.Errorf("invalid host address %q: port must not be empty", p.Host)
+		}
+	case PortProtocolUnix:
+		p.Host = strings.TrimPrefix(p.Host, "unix://")
+		if !filepath.IsAbs(p.Host) {
+			return PortForward{}, fmt.Errorf("invalid host socket %q: want an absolute path", p.Host)
+		}
+	default:
+		return PortForward{}, fmt.Errorf("unsupported protocol %q, want %s, %s or %s", p.Protocol, PortProtocolTCP, PortProtocolUDP, PortProtocolUnix)
+	}
+	return p, nil
+}
@@ -0,0 +1,70 @@
+package protocol
+
+import "time"
+
+// GuestStatsCommand is the guest agent subcommand that prints GuestStats as
+// one JSON object per line. With a non-zero GuestStatsIntervalFlag it keeps
+// printing one sample per interval.
+const (
+	GuestStatsCommand      = "stats"
+	GuestStatsIntervalFlag = "interval"
+)
+
+// GuestStats is a snapshot of the resources used inside the guest.
+type GuestStats struct {
+	Time time.Time `json:"time"`
+	CPU  GuestCPU  `json:"cpu"`
+	// Memory is read from /proc/meminfo.
+	Memory GuestMemory `json:"memory"`
+	Load   GuestLoad   `json:"load"`
+	// Processes is the number of processes in the guest.
+	Processes int `json:"processes"`
+	// Cgroup holds the root cgroup v2 accounting; nil if not mounted.
+	Cgroup *GuestCgroup `json:"cgroup,omitempty"`
+	// Disks holds one entry per mounted block device.
+	Disks []GuestDisk `json:"disks,omitempty"`
+}
+
+// GuestCPU is the CPU time spent between two /proc/stat samples, in percent
+// of all cores.
+type GuestCPU struct {
+	Cores        int     `json:"cores"`
+	UsagePercent float64 `json:"usagePercent"`
+	User         float64 `json:"user"`
+	System       float64 `json:"system"`
+	IOWait       float64 `json:"iowait"`
+	Steal        float64 `json:"steal"`
+}
+
+type GuestMemory struct {
+	TotalBytes     uint64 `json:"totalBytes"`
+	UsedBytes      uint64 `json:"usedBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+	FreeBytes      uint64 `json:"freeBytes"`
+	CachedBytes    uint64 `json:"cachedBytes"`
+	SwapTotalBytes uint64 `json:"swapTotalBytes"`
+	SwapFreeBytes  uint64 `json:"swapFreeBytes"`
+}
+
+type GuestLoad struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+type GuestCgroup struct {
+	CPUUsageUsec    uint64 `json:"cpuUsageUsec"`
+	MemoryAnonBytes uint64 `json:"memoryAnonBytes"`
+	MemoryFileBytes uint64 `json:"memoryFileBytes"`
+}
+
+type GuestDisk struct {
+	Path           string `json:"path,omitempty"`
+	MountPoint     string `json:"mountPoint"`
+	FsType         string `json:"fsType,omitempty"`
+	TotalBytes     uint64 `json:"totalBytes"`
+	UsedBytes      uint64 `json:"usedBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+	InodesTotal    uint64 `json:"inodesTotal"`
+	InodesFree     uint64 `json:"inodesFree"`
+}
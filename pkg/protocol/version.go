@@ -0,0 +1,116 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Oldest schema versions this build still produces and accepts. The newest
+// are AttachSpecVersion, GuestSpecVersion and ExecProtocolVersion.
+const (
+	AttachSpecMinVersion   = 1
+	GuestSpecMinVersion    = 1
+	ExecProtocolMinVersion = 1
+)
+
+// SchemaVersionQuery is the query parameter that selects the schema version
+// of a versioned response, such as /v2/attach or the guest /vmconfig.
+const SchemaVersionQuery = "schemaVersion"
+
+// SchemaRange is the range of versions of a schema that one side supports.
+type SchemaRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Supported versions of the schemas of this build.
+var (
+	AttachSpecRange   = SchemaRange{Min: AttachSpecMinVersion, Max: AttachSpecVersion}
+	GuestSpecRange    = SchemaRange{Min: GuestSpecMinVersion, Max: GuestSpecVersion}
+	ExecProtocolRange = SchemaRange{Min: ExecProtocolMinVersion, Max: ExecProtocolVersion}
+)
+
+// Contains reports whether version is in r.
+func (r SchemaRange) Contains(version int) bool {
+	return version >= r.Min && version <= r.Max
+}
+
+// Negotiate returns the newest version supported by both r and peer.
+func (r SchemaRange) Negotiate(peer SchemaRange) (int, error) {
+	version := min(r.Max, peer.Max)
+	if version < r.Min || version < peer.Min {
+		return 0, fmt.Errorf("no common schema version: we support %d-%d, the peer supports %d-%d", r.Min, r.Max, peer.Min, peer.Max)
+	}
+	return version, nil
+}
+
+// ParseSchemaVersion parses the value of SchemaVersionQuery. An empty value
+// selects the newest version in supported.
+func ParseSchemaVersion(value string, supported SchemaRange) (int, error) {
+	if value == "" {
+		return supported.Max, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", SchemaVersionQuery, value)
+	}
+	if !supported.Contains(version) {
+		return 0, fmt.Errorf("unsupported schema version %d: supported %d-%d", version, supported.Min, supported.Max)
+	}
+	return version, nil
+}
+
+// VersionInfo describes a revm build and what it supports. It is served by
+// the management API at /v2/version and to the guest agent by the ignition
+// server, so that either side can negotiate schema versions.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	// Platform is the host GOOS/GOARCH.
+	Platform string         `json:"platform"`
+	Schemas  SchemaVersions `json:"schemas"`
+	Libkrun  LibkrunInfo    `json:"libkrun"`
+	Features FeatureSupport `json:"features"`
+}
+
+// SchemaVersions lists the supported versions of each versioned schema.
+type SchemaVersions struct {
+	AttachSpec   SchemaRange `json:"attachSpec"`
+	GuestSpec    SchemaRange `json:"guestSpec"`
+	ExecProtocol SchemaRange `json:"execProtocol"`
+}
+
+// LibkrunInfo describes the linked libkrun library.
+type LibkrunInfo struct {
+	// AssetsRelease is the revm-assets release the library was taken from;
+	// libkrun itself does not report its version.
+	AssetsRelease string `json:"assetsRelease"`
+	// Features lists the optional libkrun features compiled in, such as net
+	// or gpu.
+	Features []string `json:"features,omitempty"`
+	MaxVCPUs int      `json:"maxVCPUs,omitempty"`
+}
+
+// FeatureSupport lists what this build can do.
+type FeatureSupport struct {
+	NetworkModes []string `json:"networkModes"`
+	RunModes     []string `json:"runModes"`
+	// Endpoints lists the management API routes; empty when served to the
+	// guest.
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// LegacyVersionInfo is assumed for a peer that predates version negotiation
+// and answers 404 to a version request: it only knows the first version of
+// every schema.
+func LegacyVersionInfo() VersionInfo {
+	return VersionInfo{
+		Schemas: SchemaVersions{
+			AttachSpec:   SchemaRange{Min: 1, Max: 1},
+			GuestSpec:    SchemaRange{Min: 1, Max: 1},
+			ExecProtocol: SchemaRange{Min: 1, Max: 1},
+		},
+	}
+}
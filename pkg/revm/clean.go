@@ -0,0 +1,186 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/sirupsen/logrus"
+)
+
+// CleanOptions controls which abandoned sessions CleanSessions reclaims.
+type CleanOptions struct {
+	// OlderThan skips sessions used more recently than this; zero means no age filter.
+	OlderThan time.Duration
+	// IncludeDisks also removes the built-in container storage disk.
+	IncludeDisks bool
+	// DryRun reports what would be removed without touching the filesystem.
+	DryRun bool
+}
+
+// CleanResult describes what CleanSessions did (or would do) for one session.
+type CleanResult struct {
+	ID        string    `json:"id"`
+	Workspace string    `json:"workspace"`
+	LastUsed  time.Time `json:"lastUsed"`
+
+	// Removed lists the workspace paths that were (or would be) removed.
+	Removed []string `json:"removed,omitempty"`
+	// ReclaimedBytes is the apparent size of the removed paths.
+	ReclaimedBytes int64 `json:"reclaimedBytes"`
+	// Skipped records why the session was left untouched.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// CleanSessions removes the leftovers of every session under the session root
+// whose lock can be acquired. Logs are kept so that a dead session can still be
+// inspected; the workspace directory itself goes away once it is empty.
+func CleanSessions(ctx context.Context, opts CleanOptions) ([]CleanResult, error) {
+	ids, err := sessionIDs(getSessionRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CleanResult, 0, len(ids))
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, err := cleanSession(id, opts)
+		if err != nil {
+			return results, fmt.Errorf("clean session %q: %w", id, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func cleanSession(id string, opts CleanOptions) (CleanResult, error) {
+	workspace := getSessionDir(id)
+	lockPath := workspace + sessionLockSuffix
+	result := CleanResult{
+		ID:        id,
+		Workspace: workspace,
+		LastUsed:  sessionLastUsed(workspace),
+	}
+
+	if opts.OlderThan > 0 && time.Since(result.LastUsed) < opts.OlderThan {
+		result.Skipped = fmt.Sprintf("used within %s", opts.OlderThan)
+		return result, nil
+	}
+
+	// A dry run must not leave behind a lock file it had to create.
+	_, statErr := os.Stat(lockPath)
+	lockExisted := statErr == nil
+
+	// Hold the session lock for the whole removal so a launcher can not start a
+	// new session with the same name while its workspace is being deleted.
+	fileLock := flock.New(lockPath)
+	locked, err := fileLock.TryLock()
+	if err != nil {
+		return result, fmt.Errorf("get lock failed: %w", err)
+	}
+	if !locked {
+		result.Skipped = string(SessionRunning)
+		return result, nil
+	}
+	defer func() {
+		_ = fileLock.Unlock()
+		if !opts.DryRun || !lockExisted {
+			_ = os.Remove(lockPath)
+		}
+	}()
+
+	pathMgr := newMachinePathManager(workspace)
+	targets := []string{
+		pathMgr.GetRootfsDir(),
+		filepath.Dir(pathMgr.GetVMCtlSocketFile()),
+		filepath.Dir(pathMgr.GetSSHKeyFilePath()),
+		// Spill files are only removed by a launcher that shuts down cleanly.
+		pathMgr.GetEventSpillDir(),
+	}
+	if opts.IncludeDisks {
+		targets = append(targets, pathMgr.GetBuiltInContainerStorageDiskFile())
+	}
+
+	for _, target := range targets {
+		size, err := pathSize(target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return result, err
+		}
+
+		if !opts.DryRun {
+			logrus.Debugf("remove %q", target)
+			if err := os.RemoveAll(target); err != nil {
+				return result, fmt.Errorf("remove %q: %w", target, err)
+			}
+		}
+		result.Removed = append(result.Removed, target)
+		result.ReclaimedBytes += size
+	}
+
+	if !opts.DryRun {
+		// Only succeeds once nothing but empty parents are left, e.g. raw-disk/.
+		_ = os.Remove(filepath.Dir(pathMgr.GetBuiltInContainerStorageDiskFile()))
+		_ = os.Remove(workspace)
+	}
+	return result, nil
+}
+
+// sessionLastUsed returns the newest modification time among the workspace,
+// its lock file and its log files.
+func sessionLastUsed(workspace string) time.Time {
+	var latest time.Time
+	observe := func(path string) {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	observe(workspace)
+	observe(workspace + sessionLockSuffix)
+
+	logsDir := newMachinePathManager(workspace).GetLogsDir()
+	observe(logsDir)
+	if entries, err := os.ReadDir(logsDir); err == nil {
+		for _, entry := range entries {
+			observe(filepath.Join(logsDir, entry.Name()))
+		}
+	}
+	return latest
+}
+
+// pathSize returns the apparent size of path, walking it if it is a directory.
+func pathSize(path string) (int64, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return 0, err
+	}
+
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("walk %q: %w", path, err)
+	}
+	return size, nil
+}
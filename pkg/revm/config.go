@@ -3,15 +3,19 @@
 package revm
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"linuxvm/pkg/network"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
 // RunMode selects the VM run mode.
@@ -51,15 +55,23 @@ type Config struct {
 
 	Network              string             `json:"network,omitempty"` // "gvisor" | "tsi"
 	Mounts               []string           `json:"mounts,omitempty"`  // "/host:/guest[,ro]"
+	Publish              []string           `json:"publish,omitempty"` // "[hostIP:]hostPort:guestPort[/tcp|/udp]"
 	Disks                []RawDiskSpec      `json:"disks,omitempty"`
 	ContainerDisk        *ContainerDiskSpec `json:"containerDisk,omitempty"`
 	PodmanProxyAPIFile   string             `json:"podmanProxyAPIFile,omitempty"`
 	ManageAPIFile        string             `json:"manageAPIFile,omitempty"`
 	SSHKeyFileSymbolPath string             `json:"SSHKeyFileSymbolPath,omitempty"`
-	ReportURL            string             `json:"reportURL,omitempty"`
+	ReportURL            string             `json:"reportURL,omitempty"`  // single reporter, same format as ReportURLs
+	ReportURLs           []string           `json:"reportURLs,omitempty"` // "<endpoint>[;version=1|2][;kind=<kind>,...]"
+	AllowUIDs            []uint32           `json:"allowUIDs,omitempty"`  // extra users admitted to the API sockets
+	AllowGIDs            []uint32           `json:"allowGIDs,omitempty"`  // extra groups admitted to the API sockets
 	Proxy                bool               `json:"proxy,omitempty"`
 	LogLevel             string             `json:"logLevel,omitempty"` // default "info"
 	LogTo                string             `json:"logTo,omitempty"`
+
+	// reporters are in-process reporters added by WithEventReporter. They
+	// are not serialized, so a detached launcher does not inherit them.
+	reporters []EventReporter
 }
 
 // DefaultConfig returns a Config with sensible defaults pre-filled.
@@ -171,11 +183,52 @@ func (c *Config) WithExportSSHKeyPrivateFile(path string) *Config {
 	return c
 }
 
-func (c *Config) WithEventReporter(reportURL string) *Config {
-	if reportURL == "" {
+// WithReportURL reports lifecycle events to each endpoint, see ReportURLs and
+// --report-events. Empty values are ignored.
+func (c *Config) WithReportURL(reportURLs ...string) *Config {
+	for _, u := range reportURLs {
+		if u != "" {
+			c.ReportURLs = append(c.ReportURLs, u)
+		}
+	}
+	return c
+}
+
+// WithEventReporter passes lifecycle events to r within this process, only
+// those of the given kinds if any. The VM closes r on Release.
+func (c *Config) WithEventReporter(r EventReporter, kinds ...EventKind) *Config {
+	if r == nil {
 		return c
 	}
-	c.ReportURL = reportURL
+	c.reporters = append(c.reporters, filterEvents(r, kinds))
+	return c
+}
+
+// reportURLs returns every configured reporter spec.
+func (c *Config) reportURLs() []string {
+	if c.ReportURL == "" {
+		return c.ReportURLs
+	}
+	return append([]string{c.ReportURL}, c.ReportURLs...)
+}
+
+// peerAllowlist returns the local users admitted to the management and Podman
+// API sockets and to runtime unix port forwards; root and the launcher's user
+// are always admitted.
+func (c *Config) peerAllowlist() network.PeerAllowlist {
+	return network.PeerAllowlist{UIDs: c.AllowUIDs, GIDs: c.AllowGIDs}
+}
+
+// WithAllowUIDs admits the given users to the session's API sockets.
+func (c *Config) WithAllowUIDs(uids ...uint32) *Config {
+	c.AllowUIDs = append(c.AllowUIDs, uids...)
+	return c
+}
+
+// WithAllowGIDs admits members of the given groups to the session's API
+// sockets.
+func (c *Config) WithAllowGIDs(gids ...uint32) *Config {
+	c.AllowGIDs = append(c.AllowGIDs, gids...)
 	return c
 }
 
@@ -195,7 +248,7 @@ func (c *Config) WithLogging(level string, logFilePath string) *Config {
 	c.LogLevel = level
 
 	if logFilePath == "" {
-		logFilePath = filepath.Join(getSessionDir(c.SessionID), "logs", "revm.log")
+		logFilePath = newMachinePathManager(getSessionDir(c.SessionID)).GetHostLogFile()
 	}
 	c.LogTo = logFilePath
 
@@ -248,6 +301,8 @@ func (c *Config) WithMount(specs ...string) *Config {
 	return c
 }
 
+// WithRawDiskSpecs attaches raw disks; a spec whose path is already attached
+// replaces the earlier one.
 func (c *Config) WithRawDiskSpecs(specs ...RawDiskSpec) *Config {
 	if len(specs) == 0 {
 		return c
@@ -256,13 +311,69 @@ func (c *Config) WithRawDiskSpecs(specs ...RawDiskSpec) *Config {
 		if spec.Path == "" {
 			continue
 		}
-		c.Disks = append(c.Disks, spec)
+		replaced := false
+		for i := range c.Disks {
+			if c.Disks[i].Path == spec.Path {
+				c.Disks[i] = spec
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.Disks = append(c.Disks, spec)
+		}
 	}
 	return c
 }
 
 // --- Loading ---------------------------------------------------------------
 
+// LoadConfig reads a Config from a JSON file, or a YAML file when path ends in
+// .yaml or .yml. Both formats use the JSON field names. Fields missing from
+// the file keep their DefaultConfig values, and relative host paths (rootfs,
+// mount sources, disks, socket and log files) are resolved against the
+// directory of the file.
+func LoadConfig(path string) (*Config, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve config file path: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse config file %q: %w", path, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("parse config file %q: %w", path, err)
+	}
+	cfg.resolvePaths(filepath.Dir(path))
+	return cfg, nil
+}
+
+// yamlToJSON re-encodes a YAML document as JSON so that YAML files are decoded
+// through the same json tags as JSON files.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(doc)
+}
+
 // WriteCfg marshals cfg as JSON and writes it to path.
 func (c *Config) WriteCfg(path string) error {
 	data, err := json.Marshal(c)
@@ -352,6 +463,21 @@ func validateConfig(cfg Config) error {
 		return fmt.Errorf("network must be \"gvisor\" or \"tsi\", got %q", cfg.Network)
 	}
 
+	if len(cfg.Publish) > 0 {
+		if cfg.Network == "tsi" {
+			return fmt.Errorf("publishing ports requires the gvisor network: in tsi mode guest ports are already reachable on the host, drop --publish or use --network gvisor")
+		}
+		if _, err := parsePublishSpecs(cfg.Publish); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range cfg.reportURLs() {
+		if _, err := parseReportTarget(spec); err != nil {
+			return fmt.Errorf("invalid event reporter %q: %w", spec, err)
+		}
+	}
+
 	return nil
 }
 
@@ -2,6 +2,11 @@
 
 package revm
 
+import (
+	"linuxvm/pkg/protocol"
+	"slices"
+)
+
 // EventKind identifies a VM lifecycle event.
 type EventKind string
 
@@ -15,4 +20,43 @@ const (
 
 	EventNetworkReady EventKind = "network_ready"
 	EventPodmanReady  EventKind = "podman_ready"
+
+	// EventStateChanged reports a lifecycle transition, see Event.State.
+	EventStateChanged EventKind = "state_changed"
+
+	// Events relayed from the guest agent, with Event.Source set to
+	// EventSourceGuest.
+	EventGuestMountsReady      = EventKind(protocol.GuestEventMountsReady)
+	EventGuestSSHReady         = EventKind(protocol.GuestEventSSHReady)
+	EventGuestCommandStarted   = EventKind(protocol.GuestEventCommandStarted)
+	EventGuestCommandExited    = EventKind(protocol.GuestEventCommandExited)
+	EventGuestServiceRestarted = EventKind(protocol.GuestEventServiceRestarted)
+)
+
+var eventKinds = []EventKind{
+	EventStopping,
+	EventManagementAPIStarting,
+	EventHostNetworkStack,
+	EventIgnitionService,
+	EventVirtualMachineBooting,
+	EventNetworkReady,
+	EventPodmanReady,
+	EventStateChanged,
+	EventGuestMountsReady,
+	EventGuestSSHReady,
+	EventGuestCommandStarted,
+	EventGuestCommandExited,
+	EventGuestServiceRestarted,
+}
+
+func (k EventKind) known() bool {
+	return slices.Contains(eventKinds, k)
+}
+
+// EventSource tells which side of the VM an event comes from.
+type EventSource string
+
+const (
+	EventSourceHost  EventSource = "host"
+	EventSourceGuest EventSource = "guest"
 )
@@ -0,0 +1,288 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	sshsvc "linuxvm/pkg/service/ssh"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CopyTo copies hostPath, a file or directory tree, into the guest directory
+// guestDir, which is created if missing. The copy keeps its base name, file
+// modes, ownership and symlinks.
+func (vm *VM) CopyTo(ctx context.Context, hostPath, guestDir string) error {
+	if _, err := os.Lstat(hostPath); err != nil {
+		return fmt.Errorf("copy to guest: %w", err)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(packTar(hostPath, writer))
+	}()
+	defer reader.Close()
+
+	if err := sshsvc.GuestTarExtract(ctx, vm.runtime.view.SSHTarget(), guestDir, reader); err != nil {
+		return fmt.Errorf("copy %q to guest %q: %w", hostPath, guestDir, err)
+	}
+	return nil
+}
+
+// CopyFrom copies guestPath, a file or directory tree, into the host
+// directory hostDir, which is created if missing. The copy keeps its base
+// name, file modes and symlinks; ownership is restored when running as root.
+func (vm *VM) CopyFrom(ctx context.Context, guestPath, hostDir string) error {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := unpackTar(reader, hostDir)
+		// Unblock the guest side if unpacking stopped early.
+		_ = reader.CloseWithError(err)
+		done <- err
+	}()
+
+	err := sshsvc.GuestTarCreate(ctx, vm.runtime.view.SSHTarget(), guestPath, writer)
+	_ = writer.CloseWithError(err)
+	if unpackErr := <-done; unpackErr != nil && err == nil {
+		err = unpackErr
+	}
+	if err != nil {
+		return fmt.Errorf("copy guest %q to %q: %w", guestPath, hostDir, err)
+	}
+	return nil
+}
+
+// packTar writes src and, for a directory, everything below it to w. Entry
+// names start with the base name of src. Symlinks are stored, not followed.
+func packTar(src string, w io.Writer) error {
+	src = filepath.Clean(src)
+	parent := filepath.Dir(src)
+	tw := tar.NewWriter(w)
+
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("tar header for %q: %w", p, err)
+		}
+		name, err := filepath.Rel(parent, p)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Format = tar.FormatPAX
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// unpackTar extracts the tar stream r below dst. Entries that would land
+// outside dst, directly or through a symlink, are rejected.
+func unpackTar(r io.Reader, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	type dirMeta struct {
+		path    string
+		mode    os.FileMode
+		modTime time.Time
+	}
+	var dirs []dirMeta
+	chown := os.Geteuid() == 0
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read tar: %w", err)
+		}
+
+		target, err := tarEntryPath(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if target == dst {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			created, err := mkdirTarEntry(target)
+			if err != nil {
+				return err
+			}
+			// Directories that were already there keep their metadata.
+			if created {
+				dirs = append(dirs, dirMeta{path: target, mode: mode.Perm() | mode&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky), modTime: hdr.ModTime})
+			}
+		case tar.TypeReg:
+			if err := writeTarFile(target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			_ = os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := tarEntryPath(dst, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			// link(2) follows a symlink source on darwin, which would make
+			// the hard link point at whatever the symlink names.
+			if info, err := os.Lstat(source); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("tar entry %q links to symlink %q", hdr.Name, hdr.Linkname)
+			}
+			_ = os.Remove(target)
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			// Devices and FIFOs need privileges the copy does not assume.
+			continue
+		}
+
+		if chown {
+			if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+				return err
+			}
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := os.Chmod(target, mode.Perm()|mode&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+				return err
+			}
+			_ = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		}
+	}
+
+	// Apply directory modes last so read-only directories can be filled,
+	// deepest first so parent times survive. A later entry may have replaced
+	// a directory with a symlink, which must not be followed.
+	slices.Reverse(dirs)
+	for _, d := range dirs {
+		info, err := os.Lstat(d.path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("directory %q was replaced during extraction", d.path)
+		}
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return err
+		}
+		_ = os.Chtimes(d.path, d.modTime, d.modTime)
+	}
+	return nil
+}
+
+// mkdirTarEntry makes target a real directory and reports whether it had to
+// create it. Anything else in its place, such as a symlink from an earlier
+// entry, is removed first.
+func mkdirTarEntry(target string) (bool, error) {
+	info, err := os.Lstat(target)
+	switch {
+	case err == nil && info.IsDir():
+		return false, nil
+	case err == nil:
+		if err := os.Remove(target); err != nil {
+			return false, err
+		}
+	case !os.IsNotExist(err):
+		return false, err
+	}
+	if err := os.Mkdir(target, 0700); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func writeTarFile(target string, r io.Reader, mode os.FileMode) error {
+	_ = os.Remove(target)
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode.Perm()|0200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// tarEntryPath resolves name below dst and makes sure neither the name nor an
+// already extracted symlink on the way leads outside dst.
+func tarEntryPath(dst, name string) (string, error) {
+	target := filepath.Join(dst, filepath.FromSlash(name))
+	if target != dst && !strings.HasPrefix(target, dst+string(filepath.Separator)) {
+		return "", fmt.Errorf("tar entry %q escapes %q", name, dst)
+	}
+
+	rel, _ := filepath.Rel(dst, filepath.Dir(target))
+	current := dst
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." || part == "" {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("tar entry %q passes through symlink %q", name, current)
+		}
+	}
+	return target, nil
+}
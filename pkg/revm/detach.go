@@ -0,0 +1,220 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"linuxvm/pkg/client"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/service/management"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	envDetachedChild   = "REVM_DETACHED_CHILD"
+	detachReadyTimeout = 3 * time.Minute
+	detachLogTailLines = 20
+)
+
+// DetachedSession describes a session started by Detach.
+type DetachedSession struct {
+	SessionID string                  `json:"sessionID"`
+	PID       int                     `json:"pid"`
+	Workspace string                  `json:"workspace"`
+	LogFile   string                  `json:"logFile"`
+	Endpoints management.EndpointView `json:"endpoints"`
+	// Finished is set when the session ran and exited cleanly before it was
+	// seen ready, as a short command does. Endpoints are then empty and PID
+	// no longer runs.
+	Finished bool `json:"finished,omitempty"`
+}
+
+// IsDetachedChild reports whether the current process is a launcher that
+// Detach re-executed in the background.
+func IsDetachedChild() bool {
+	return os.Getenv(envDetachedChild) == "1"
+}
+
+// Detach re-executes the current launcher with args as a daemon in its own
+// session and waits until the management API reports every component of the
+// VM ready, see management.HealthView.
+//
+// The launcher keeps running after the caller exits. Its stdout and stderr go
+// to logs/detach.log in the session workspace. If the launcher exits or the VM
+// does not become ready in time, the error carries the tail of that log. A
+// launcher that exits cleanly after its VM got running is not an error, see
+// DetachedSession.Finished.
+func Detach(ctx context.Context, cfg *Config, args []string) (*DetachedSession, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config must not be nil")
+	}
+	if cfg.SessionID == "" {
+		return nil, fmt.Errorf("session name must not be empty, flag --id is required")
+	}
+	if cfg.RunMode == ModeAttach {
+		return nil, fmt.Errorf("attach mode can not be detached")
+	}
+	if len(cfg.reporters) > 0 {
+		return nil, fmt.Errorf("in-process event reporters can not follow a detached launcher, use report URLs instead")
+	}
+
+	workspace := getSessionDir(cfg.SessionID)
+	running, err := isSessionLocked(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, fmt.Errorf("session %q is locked by another instance", workspace)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("resolve launcher executable: %w", err)
+	}
+
+	logPath := filepath.Join(newMachinePathManager(workspace).GetLogsDir(), "detach.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open detach log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), envDetachedChild+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// A new session keeps terminal signals such as Ctrl-C and SIGHUP away
+	// from the daemon once the caller goes away.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start detached launcher: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, detachReadyTimeout)
+	defer cancel()
+
+	session := &DetachedSession{
+		SessionID: cfg.SessionID,
+		PID:       cmd.Process.Pid,
+		Workspace: workspace,
+		LogFile:   logPath,
+	}
+	view, err := waitDetachedReady(ctx, workspace, exited)
+	if errors.Is(err, errLauncherCompleted) && launchFinished(cfg.SessionID, started) {
+		session.Finished = true
+		return session, nil
+	}
+	if err != nil {
+		if !errors.Is(err, errLauncherExited) {
+			_ = cmd.Process.Kill()
+			<-exited
+		}
+		if tail := tailFile(logPath, detachLogTailLines); tail != "" {
+			err = fmt.Errorf("%w, last lines of %s:\n%s", err, logPath, tail)
+		}
+		return nil, err
+	}
+
+	session.Endpoints = view.Endpoints
+	return session, nil
+}
+
+var (
+	errLauncherExited = errors.New("launcher exited before the VM became ready")
+	// errLauncherCompleted is errLauncherExited with exit status 0.
+	errLauncherCompleted = fmt.Errorf("%w with exit status 0", errLauncherExited)
+)
+
+func waitDetachedReady(ctx context.Context, workspace string, exited <-chan error) (management.VMConfigView, error) {
+	ticker := time.NewTicker(define.DefaultTimeTicker)
+	defer ticker.Stop()
+
+	for {
+		if view, ok := probeDetachedReady(ctx, workspace); ok {
+			return view, nil
+		}
+
+		select {
+		case err := <-exited:
+			if err != nil {
+				return management.VMConfigView{}, fmt.Errorf("%w: %v", errLauncherExited, err)
+			}
+			return management.VMConfigView{}, errLauncherCompleted
+		case <-ctx.Done():
+			return management.VMConfigView{}, fmt.Errorf("wait for VM ready: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func probeDetachedReady(ctx context.Context, workspace string) (management.VMConfigView, bool) {
+	c := client.New(newMachinePathManager(workspace).GetVMCtlSocketFile())
+	defer c.Close()
+
+	health, err := c.Health(ctx, 0)
+	if err != nil || !health.Ready {
+		return management.VMConfigView{}, false
+	}
+	view, err := c.VMConfig(ctx)
+	if err != nil {
+		return management.VMConfigView{}, false
+	}
+	return view, true
+}
+
+// launchFinished reports whether the launch of sessionID started at since
+// got its VM running, or its command through, before the launcher exited.
+// The events journal outlives the launcher, so it is the only witness left.
+func launchFinished(sessionID string, since time.Time) bool {
+	events, err := ReadEvents(sessionID)
+	if err != nil {
+		return false
+	}
+	finished := false
+	for _, evt := range events {
+		if evt.Time.Before(since) {
+			continue
+		}
+		switch {
+		case evt.Kind == EventStateChanged && evt.State == management.StateBuilding:
+			// Each launch starts over in the building state.
+			finished = false
+		case evt.Kind == EventStateChanged && evt.State == management.StateRunning,
+			evt.Kind == EventGuestCommandExited:
+			finished = true
+		}
+	}
+	return finished
+}
+
+// tailFile returns the last n lines of path, or an empty string on error.
+func tailFile(path string, n int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return string(bytes.Join(lines, []byte("\n")))
+}
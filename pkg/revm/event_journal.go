@@ -0,0 +1,184 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	journalReporterName = "events-journal"
+
+	// eventJournalMaxSize is the size at which the journal is rotated.
+	eventJournalMaxSize = 4 * 1024 * 1024
+	// eventJournalBackups is the number of rotated journals kept.
+	eventJournalBackups = 3
+)
+
+// journalEventReporter appends every event as one JSON line to the journal in
+// the session workspace, so that the events of a session can be read back
+// with ReadEvents after it has ended. The journal survives restarts of the
+// session; every launch starts again at Seq 1 with EventStateChanged to
+// management.StateBuilding.
+type journalEventReporter struct {
+	path   string
+	failed atomic.Uint64
+
+	// mu guards file and size: the dispatcher may Close the reporter while a
+	// Report that outlived the flush deadline is still writing.
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newJournalEventReporter(path string) (*journalEventReporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create event journal directory: %w", err)
+	}
+	r := &journalEventReporter{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *journalEventReporter) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open event journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat event journal: %w", err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *journalEventReporter) Report(evt Event) {
+	line, err := json.Marshal(evt)
+	if err != nil {
+		r.failed.Add(1)
+		logrus.Warnf("event journal: encode %s failed: %v", evt.Kind, err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil && r.size > 0 && r.size+int64(len(line)) > eventJournalMaxSize {
+		r.rotate()
+	}
+	if r.file == nil {
+		r.failed.Add(1)
+		return
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		r.failed.Add(1)
+		logrus.Warnf("event journal: write %s failed: %v", evt.Kind, err)
+	}
+}
+
+// rotate renames the journal to .1, shifting older ones up to
+// eventJournalBackups, and starts a new one. On failure the journal is
+// closed and later events are counted as failed. It is called with r.mu held.
+func (r *journalEventReporter) rotate() {
+	_ = r.file.Close()
+	r.file = nil
+
+	for i := eventJournalBackups - 1; i > 0; i-- {
+		_ = os.Rename(journalBackup(r.path, i), journalBackup(r.path, i+1))
+	}
+	if err := os.Rename(r.path, journalBackup(r.path, 1)); err != nil {
+		logrus.Warnf("event journal: rotate failed: %v", err)
+	}
+	if err := r.open(); err != nil {
+		logrus.Warnf("event journal: %v", err)
+	}
+}
+
+func (r *journalEventReporter) failedEvents() uint64 {
+	return r.failed.Load()
+}
+
+func (r *journalEventReporter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		logrus.Warnf("event journal: close failed: %v", err)
+	}
+	r.file = nil
+}
+
+func journalBackup(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// ReadEvents returns the events journaled for sessionID, oldest first,
+// including those of earlier launches of the session that are still within
+// the rotated journals. It works whether or not the session is running.
+//
+// Lines that do not decode, such as the last one written by a launcher that
+// was killed mid-write, are skipped.
+func ReadEvents(sessionID string) ([]Event, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session name must not be empty")
+	}
+
+	path := newMachinePathManager(getSessionDir(sessionID)).GetEventJournalFile()
+	var events []Event
+	for i := eventJournalBackups; i >= 0; i-- {
+		file := path
+		if i > 0 {
+			file = journalBackup(path, i)
+		}
+		var err error
+		if events, err = readJournal(file, events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func readJournal(path string, events []Event) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return events, nil
+		}
+		return nil, fmt.Errorf("open event journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), eventJournalMaxSize)
+	for scanner.Scan() {
+		var evt Event
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			logrus.Debugf("event journal: skip malformed line in %s: %v", path, err)
+			continue
+		}
+		events = append(events, evt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %q: %w", path, err)
+	}
+	return events, nil
+}
@@ -4,30 +4,124 @@ package revm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"linuxvm/pkg/network"
+	"linuxvm/pkg/service/management"
+	ssev2 "linuxvm/pkg/sse"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Event protocols, selected per reporter by the endpoint scheme or the
+// version option of the reporter spec.
+const (
+	// eventProtocolV1 sends each event as GET /v1/event with query parameters.
+	eventProtocolV1 = 1
+	// eventProtocolV2 POSTs CloudEvents JSON bodies in batches with retries.
+	eventProtocolV2 = 2
+)
+
+// reportTarget is a parsed event reporter spec:
+//
+//	<endpoint>[;version=1|2][;kind=<kind>,...][;overflow=<policy>]
+//
+// unix:// and tcp:// endpoints speak eventProtocolV1 unless version=2 is
+// given; http:// and https:// endpoints only speak eventProtocolV2. kind
+// limits the reporter to the listed event kinds. overflow is the
+// OverflowPolicy of the reporter queue, block by default.
+type reportTarget struct {
+	endpoint string
+	version  int
+	kinds    []EventKind
+	overflow OverflowPolicy
+}
+
+func parseReportTarget(spec string) (reportTarget, error) {
+	parts := strings.Split(spec, ";")
+	target := reportTarget{endpoint: strings.TrimSpace(parts[0]), overflow: OverflowBlock}
+	httpEndpoint := strings.HasPrefix(target.endpoint, "http://") || strings.HasPrefix(target.endpoint, "https://")
+
+	switch {
+	case httpEndpoint:
+		target.version = eventProtocolV2
+	case strings.HasPrefix(target.endpoint, "unix://") || strings.HasPrefix(target.endpoint, "unixgram://") ||
+		strings.HasPrefix(target.endpoint, "tcp://"):
+		target.version = eventProtocolV1
+	default:
+		return reportTarget{}, fmt.Errorf("unsupported endpoint scheme %q", target.endpoint)
+	}
+
+	for _, option := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "version":
+			switch value {
+			case "1", "v1":
+				if httpEndpoint {
+					return reportTarget{}, fmt.Errorf("%q only supports event protocol version 2", target.endpoint)
+				}
+				target.version = eventProtocolV1
+			case "2", "v2":
+				target.version = eventProtocolV2
+			default:
+				return reportTarget{}, fmt.Errorf("invalid event protocol version %q", value)
+			}
+		case "kind":
+			for _, kind := range strings.Split(value, ",") {
+				kind := EventKind(strings.TrimSpace(kind))
+				if !kind.known() {
+					return reportTarget{}, fmt.Errorf("unknown event kind %q", kind)
+				}
+				target.kinds = append(target.kinds, kind)
+			}
+		case "overflow":
+			policy, err := parseOverflowPolicy(value)
+			if err != nil {
+				return reportTarget{}, err
+			}
+			target.overflow = policy
+		case "":
+		default:
+			return reportTarget{}, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return target, nil
+}
+
 type v1EventReporter struct {
 	client *network.Client
+	failed atomic.Uint64
 }
 
-func newEventReporter(endpoint string) EventReporter {
-	if endpoint == "" {
-		return nil
+// name identifies the reporter of target in logs, with any password in the
+// endpoint masked.
+func (target reportTarget) name() string {
+	if u, err := url.Parse(target.endpoint); err == nil {
+		return u.Redacted()
+	}
+	return target.endpoint
+}
+
+// newReporter creates the reporter for t; it returns nil if the endpoint is
+// unusable, which is logged.
+func (target reportTarget) newReporter() EventReporter {
+	if target.version == eventProtocolV2 {
+		return filterEvents(newV2EventReporter(target.endpoint), target.kinds)
 	}
-	client := newEventReporterClient(endpoint)
+
+	client := newEventReporterClient(target.endpoint, 1*time.Second)
 	if client == nil {
 		return nil
 	}
-	return &v1EventReporter{client: client}
+	return filterEvents(&v1EventReporter{client: client}, target.kinds)
 }
 
-func newEventReporterClient(endpoint string) *network.Client {
+func newEventReporterClient(endpoint string, timeout time.Duration) *network.Client {
 	switch {
 	case strings.HasPrefix(endpoint, "unix://") || strings.HasPrefix(endpoint, "unixgram://"):
 		addr, err := network.ParseUnixAddr(endpoint)
@@ -35,7 +129,7 @@ func newEventReporterClient(endpoint string) *network.Client {
 			logrus.Warnf("event sink: invalid unix endpoint %q: %v", endpoint, err)
 			return nil
 		}
-		return network.NewUnixClient(addr.Path, network.WithTimeout(1*time.Second))
+		return network.NewUnixClient(addr.Path, network.WithTimeout(timeout))
 	case strings.HasPrefix(endpoint, "tcp://"):
 		addr, err := network.ParseTcpAddr(endpoint)
 		if err != nil {
@@ -43,7 +137,7 @@ func newEventReporterClient(endpoint string) *network.Client {
 			return nil
 		}
 		hostPort := fmt.Sprintf("%s:%d", addr.Host, addr.Port)
-		return network.NewTCPClient(hostPort, network.WithTimeout(1*time.Second))
+		return network.NewTCPClient(hostPort, network.WithTimeout(timeout))
 	default:
 		logrus.Warnf("event sink: unsupported endpoint scheme %q", endpoint)
 		return nil
@@ -55,19 +149,59 @@ func (r *v1EventReporter) Report(evt Event) {
 		Query("session_id", evt.SessionID).
 		Query("mode", string(evt.RunMode)).
 		Query("kind", string(evt.Kind)).
+		Query("source", string(evt.Source)).
 		Query("seq", fmt.Sprintf("%d", evt.Seq)).
 		Query("msg", evt.Message).
 		Query("time", evt.Time.Format(time.RFC3339Nano))
 	resp, err := req.Do(context.Background()) //nolint:bodyclose
 	if err != nil {
+		r.failed.Add(1)
 		logrus.Warnf("v1 event sink: emit %s failed: %v", evt.Kind, err)
 		return
 	}
 	network.CloseResponse(resp)
 }
 
+func (r *v1EventReporter) failedEvents() uint64 {
+	return r.failed.Load()
+}
+
 func (r *v1EventReporter) Close() {
 	if err := r.client.Close(); err != nil {
 		logrus.Warnf("v1 event sink: close failed: %v", err)
 	}
 }
+
+// eventStreamHistory bounds how many events /v2/events replays to a client
+// that connects late.
+const eventStreamHistory = 256
+
+// streamReporterName names the /v2/events reporter in metrics.
+const streamReporterName = "events-stream"
+
+// streamEventReporter publishes events to the /v2/events stream of the
+// management API.
+type streamEventReporter struct {
+	server *ssev2.Server
+}
+
+func newStreamEventReporter() *streamEventReporter {
+	return &streamEventReporter{server: ssev2.NewReplayServer(eventStreamHistory)}
+}
+
+func (r *streamEventReporter) Report(evt Event) {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		logrus.Warnf("event stream: encode %s failed: %v", evt.Kind, err)
+		return
+	}
+	r.server.Publish(management.EventsTopic, string(evt.Kind), string(data))
+}
+
+func (r *streamEventReporter) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.server.Shutdown(ctx); err != nil {
+		logrus.Debugf("event stream: shutdown: %v", err)
+	}
+}
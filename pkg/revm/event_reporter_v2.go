@@ -0,0 +1,268 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linuxvm/pkg/network"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	cloudEventsSpecVersion      = "1.0"
+	cloudEventsContentType      = "application/cloudevents+json"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+	// cloudEventsTypePrefix prefixes the event kind in the CloudEvents type.
+	cloudEventsTypePrefix = "io.github.ihexon.revm."
+
+	// v2EventsPath receives events on unix:// and tcp:// endpoints.
+	v2EventsPath = "/v2/events"
+
+	v2ReporterQueueSize    = 256
+	v2ReporterBatchMax     = 64
+	v2ReporterBatchWindow  = 50 * time.Millisecond
+	v2ReporterTimeout      = 5 * time.Second
+	v2ReporterRetryInitial = 200 * time.Millisecond
+	v2ReporterRetryMax     = 5 * time.Second
+	v2ReporterMaxAttempts  = 8
+	// v2ReporterCloseTimeout bounds how long Close keeps delivering queued
+	// events.
+	v2ReporterCloseTimeout = 5 * time.Second
+)
+
+// cloudEvent is the structured-mode CloudEvents 1.0 envelope of an Event.
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Event     `json:"data"`
+}
+
+func newCloudEvent(evt Event) cloudEvent {
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              uuid.NewString(),
+		Source:          cloudEventSource(evt),
+		Type:            cloudEventsTypePrefix + string(evt.Kind),
+		Time:            evt.Time,
+		DataContentType: "application/json",
+		Data:            evt,
+	}
+}
+
+// cloudEventSource is the session path, with a /guest suffix for events
+// relayed from the guest agent.
+func cloudEventSource(evt Event) string {
+	source := "/revm/sessions/" + evt.SessionID
+	if evt.Source == EventSourceGuest {
+		source += "/guest"
+	}
+	return source
+}
+
+// v2EventReporter POSTs events as CloudEvents. Report queues the event; a
+// sender goroutine groups the events of a burst into one batch request and
+// retries failed deliveries with exponential backoff. While the sender is
+// retrying and its queue is full, Report waits, which leaves the overflow
+// decision to the dispatcher queue of the reporter.
+type v2EventReporter struct {
+	client *network.Client
+	path   string
+	query  url.Values
+	// auth is the Authorization header for credentials given in the
+	// endpoint URL, if any.
+	auth string
+
+	mu     sync.RWMutex
+	closed bool
+	failed atomic.Uint64
+	queue  chan cloudEvent
+	done   chan struct{}
+	// ctx aborts retries once Close has waited v2ReporterCloseTimeout.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newV2EventReporter(endpoint string) EventReporter {
+	r := &v2EventReporter{path: v2EventsPath}
+
+	switch u, err := url.Parse(endpoint); {
+	case err != nil:
+		logrus.Warnf("v2 event sink: invalid endpoint %q: %v", endpoint, err)
+		return nil
+	case u.Scheme == "http" || u.Scheme == "https":
+		r.client = network.NewHTTPClient(u.Scheme+"://"+u.Host, network.WithTimeout(v2ReporterTimeout))
+		r.path = u.Path
+		r.query = u.Query()
+		if u.User != nil {
+			password, _ := u.User.Password()
+			r.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+password))
+		}
+	default:
+		r.client = newEventReporterClient(endpoint, v2ReporterTimeout)
+		if r.client == nil {
+			return nil
+		}
+	}
+
+	r.queue = make(chan cloudEvent, v2ReporterQueueSize)
+	r.done = make(chan struct{})
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run()
+	return r
+}
+
+func (r *v2EventReporter) Report(evt Event) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- newCloudEvent(evt):
+	case <-r.ctx.Done():
+		r.failed.Add(1)
+		logrus.Warnf("v2 event sink: closed, dropping event %s", evt.Kind)
+	}
+}
+
+func (r *v2EventReporter) failedEvents() uint64 {
+	return r.failed.Load()
+}
+
+func (r *v2EventReporter) run() {
+	defer close(r.done)
+	for evt := range r.queue {
+		r.deliver(r.collect(evt))
+	}
+}
+
+// collect gathers the events that arrive within v2ReporterBatchWindow of
+// first, up to v2ReporterBatchMax.
+func (r *v2EventReporter) collect(first cloudEvent) []cloudEvent {
+	batch := []cloudEvent{first}
+	timer := time.NewTimer(v2ReporterBatchWindow)
+	defer timer.Stop()
+
+	for len(batch) < v2ReporterBatchMax {
+		select {
+		case evt, ok := <-r.queue:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, evt)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// errPermanent marks a delivery the collector rejected; it is not retried.
+var errPermanent = errors.New("rejected by collector")
+
+func (r *v2EventReporter) deliver(batch []cloudEvent) {
+	var (
+		body        []byte
+		err         error
+		contentType = cloudEventsBatchContentType
+	)
+	if len(batch) == 1 {
+		body, err = json.Marshal(batch[0])
+		contentType = cloudEventsContentType
+	} else {
+		body, err = json.Marshal(batch)
+	}
+	if err != nil {
+		logrus.Warnf("v2 event sink: encode %d events failed: %v", len(batch), err)
+		return
+	}
+
+	backoff := v2ReporterRetryInitial
+	for attempt := 1; ; attempt++ {
+		err = r.post(body, contentType)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, errPermanent) || attempt == v2ReporterMaxAttempts {
+			r.failed.Add(uint64(len(batch)))
+			logrus.Warnf("v2 event sink: dropping %d events after %d attempts: %v", len(batch), attempt, err)
+			return
+		}
+		logrus.Debugf("v2 event sink: attempt %d failed, retrying in %s: %v", attempt, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			r.failed.Add(uint64(len(batch)))
+			logrus.Warnf("v2 event sink: dropping %d events on close: %v", len(batch), err)
+			return
+		}
+		backoff = min(2*backoff, v2ReporterRetryMax)
+	}
+}
+
+func (r *v2EventReporter) post(body []byte, contentType string) error {
+	req := r.client.Post(r.path).
+		Header("Content-Type", contentType).
+		Body(bytes.NewReader(body))
+	if r.auth != "" {
+		req.Header("Authorization", r.auth)
+	}
+	for key := range r.query {
+		req.Query(key, r.query.Get(key))
+	}
+
+	_, status, err := req.DoAndRead(r.ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status >= 200 && status < 300:
+		return nil
+	case status == http.StatusTooManyRequests || status >= 500:
+		return fmt.Errorf("collector returned status %d", status)
+	default:
+		return fmt.Errorf("%w: status %d", errPermanent, status)
+	}
+}
+
+// Close stops accepting events and delivers the queued ones, giving up on
+// whatever is left after v2ReporterCloseTimeout.
+func (r *v2EventReporter) Close() {
+	// Arm the deadline first: it also releases a Report waiting for room.
+	deadline := time.AfterFunc(v2ReporterCloseTimeout, r.cancel)
+	defer deadline.Stop()
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.queue)
+	r.mu.Unlock()
+
+	<-r.done
+	r.cancel()
+
+	if err := r.client.Close(); err != nil {
+		logrus.Warnf("v2 event sink: close failed: %v", err)
+	}
+}
@@ -3,14 +3,21 @@
 package revm
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"linuxvm/pkg/service/management"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-const eventQueueSize = 32
-
 // Event represents a single VM lifecycle event.
 type Event struct {
 	RunMode   RunMode   `json:"runMode"`
@@ -19,6 +26,13 @@ type Event struct {
 	SessionID string    `json:"sessionID,omitempty"`
 	Seq       uint64    `json:"seq,omitempty"`
 	Time      time.Time `json:"time"`
+	// Source is EventSourceGuest for events relayed from the guest agent.
+	Source EventSource `json:"source"`
+	// State is the lifecycle state entered, set on EventStateChanged only.
+	State management.LifecycleState `json:"state,omitempty"`
+	// ExitCode is the exit code of the user command, set on
+	// EventGuestCommandExited only.
+	ExitCode *int `json:"exitCode,omitempty"`
 }
 
 // EventReporter consumes VM lifecycle events.
@@ -27,16 +41,85 @@ type EventReporter interface {
 	Close()
 }
 
+// filteredEventReporter passes on the events of the listed kinds only.
+type filteredEventReporter struct {
+	EventReporter
+	kinds []EventKind
+}
+
+// filterEvents limits r to kinds; no kinds means every event.
+func filterEvents(r EventReporter, kinds []EventKind) EventReporter {
+	if r == nil || len(kinds) == 0 {
+		return r
+	}
+	return &filteredEventReporter{EventReporter: r, kinds: kinds}
+}
+
+func (r *filteredEventReporter) Report(evt Event) {
+	if slices.Contains(r.kinds, evt.Kind) {
+		r.EventReporter.Report(evt)
+	}
+}
+
+func (r *filteredEventReporter) failedEvents() uint64 {
+	if fc, ok := r.EventReporter.(failureCounter); ok {
+		return fc.failedEvents()
+	}
+	return 0
+}
+
+// OverflowPolicy decides what the queue of a reporter does with a new event
+// while it is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes the emitter wait for room: no event is lost, but a
+	// stuck reporter stalls whoever emits the next event.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued event.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowSpill appends events to a file in the session workspace and
+	// delivers them from there, in order, once the reporter catches up.
+	OverflowSpill OverflowPolicy = "spill-to-disk"
+)
+
+func parseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(value); policy {
+	case OverflowBlock, OverflowDropOldest, OverflowSpill:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("overflow policy must be %q, %q or %q, got %q", OverflowBlock, OverflowDropOldest, OverflowSpill, value)
+	}
+}
+
+const (
+	// reporterQueueSize bounds the in-memory queue of each reporter.
+	reporterQueueSize = 1024
+	// eventFlushTimeout bounds how long close waits for queued events to be
+	// handed to their reporters.
+	eventFlushTimeout = 5 * time.Second
+)
+
+// failureCounter is implemented by reporters that can lose events after they
+// accepted them, such as network reporters that give up retrying.
+type failureCounter interface {
+	failedEvents() uint64
+}
+
+// eventDispatcher fans events out to reporters. Every reporter has its own
+// bounded queue and goroutine, so a slow reporter delays nobody but itself,
+// and its overflow policy decides what happens when it falls behind.
 type eventDispatcher struct {
-	mu        sync.RWMutex
-	reporters []EventReporter
-	closed    bool
-	events    chan Event
-	once      sync.Once
-	wg        sync.WaitGroup
+	mu     sync.RWMutex
+	queues []*reporterQueue
+	closed bool
+	// spillDir holds the spill files of OverflowSpill queues.
+	spillDir string
 }
 
-func (d *eventDispatcher) addReporter(r EventReporter) {
+// addReporter starts delivering events to r, which is closed when the
+// dispatcher is. name identifies r in logs and metrics.
+func (d *eventDispatcher) addReporter(name string, r EventReporter, policy OverflowPolicy) {
 	if d == nil || r == nil {
 		return
 	}
@@ -47,16 +130,16 @@ func (d *eventDispatcher) addReporter(r EventReporter) {
 		r.Close()
 		return
 	}
-	d.startLocked()
-	d.reporters = append(d.reporters, r)
-}
-
-func (d *eventDispatcher) emit(sessionID string, runMode RunMode, kind EventKind, msg string, seq uint64) {
-	if d == nil {
-		return
+	if policy == "" {
+		policy = OverflowBlock
 	}
-	evt := newEvent(sessionID, runMode, kind, msg, seq)
-	d.enqueue(evt)
+	q := &reporterQueue{name: name, reporter: r, policy: policy, done: make(chan struct{})}
+	if policy == OverflowSpill {
+		q.spill = &eventSpill{path: filepath.Join(d.spillDir, fmt.Sprintf("reporter-%d.jsonl", len(d.queues)))}
+	}
+	q.cond = sync.NewCond(&q.mu)
+	d.queues = append(d.queues, q)
+	go q.run()
 }
 
 func newEvent(sessionID string, runMode RunMode, kind EventKind, msg string, seq uint64) Event {
@@ -64,49 +147,45 @@ func newEvent(sessionID string, runMode RunMode, kind EventKind, msg string, seq
 		SessionID: sessionID,
 		RunMode:   runMode,
 		Kind:      kind,
+		Source:    EventSourceHost,
 		Message:   msg,
 		Seq:       seq,
 		Time:      time.Now(),
 	}
 }
 
+// enqueue hands evt to every reporter queue. The dispatcher lock is not held
+// while a push waits for room, so close can always wake the waiting pushers.
 func (d *eventDispatcher) enqueue(evt Event) {
 	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	if d.closed || d.events == nil {
+	if d.closed {
+		d.mu.RUnlock()
 		return
 	}
+	queues := d.queues
+	d.mu.RUnlock()
 
-	select {
-	case d.events <- evt:
-	default:
-		logrus.Warnf("event sink queue full, dropping event %s", evt.Kind)
+	for _, q := range queues {
+		q.push(evt)
 	}
 }
 
-func (d *eventDispatcher) startLocked() {
-	d.once.Do(func() {
-		d.events = make(chan Event, eventQueueSize)
-		d.wg.Add(1)
-		go d.run()
-	})
-}
-
-func (d *eventDispatcher) run() {
-	defer d.wg.Done()
-	for evt := range d.events {
-		d.mu.RLock()
-		reporters := make([]EventReporter, len(d.reporters))
-		copy(reporters, d.reporters)
-		d.mu.RUnlock()
+// metrics returns the delivery metrics of every reporter.
+func (d *eventDispatcher) metrics() []management.EventReporterMetrics {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-		for _, r := range reporters {
-			r.Report(evt)
-		}
+	metrics := make([]management.EventReporterMetrics, 0, len(d.queues))
+	for _, q := range d.queues {
+		metrics = append(metrics, q.metrics())
 	}
+	return metrics
 }
 
+// close stops accepting events, waits up to eventFlushTimeout for the queued
+// ones to be handed to their reporters and closes the reporters. Events still
+// queued after the deadline are counted as dropped. A reporter is closed even
+// if its Report outlived the deadline and is still running.
 func (d *eventDispatcher) close() {
 	if d == nil {
 		return
@@ -118,19 +197,257 @@ func (d *eventDispatcher) close() {
 		return
 	}
 	d.closed = true
-	events := d.events
-	d.events = nil
-	reporters := make([]EventReporter, len(d.reporters))
-	copy(reporters, d.reporters)
-	d.reporters = nil
+	queues := d.queues
 	d.mu.Unlock()
 
-	if events != nil {
-		close(events)
-		d.wg.Wait()
+	for _, q := range queues {
+		q.close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventFlushTimeout)
+	defer cancel()
+	for _, q := range queues {
+		select {
+		case <-q.done:
+		case <-ctx.Done():
+		}
+		q.abandon()
+		q.reporter.Close()
 	}
+}
 
-	for _, r := range reporters {
-		r.Close()
+// reporterQueue is the queue of one reporter and its delivery counters.
+type reporterQueue struct {
+	name     string
+	reporter EventReporter
+	policy   OverflowPolicy
+	done     chan struct{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Event
+	spill   *eventSpill
+	closed  bool
+	// abandoned drops whatever is still queued once the flush deadline passed.
+	abandoned bool
+
+	accepted, delivered, dropped, spilled uint64
+}
+
+func (q *reporterQueue) push(evt Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			q.dropLocked(evt, "queue closed")
+			return
+		}
+		// Once events spill, later ones follow them to keep the order.
+		if q.spill != nil && (q.spill.count > 0 || len(q.pending) >= reporterQueueSize) {
+			if err := q.spill.write(evt); err != nil {
+				q.dropLocked(evt, err.Error())
+				return
+			}
+			q.accepted++
+			q.spilled++
+			q.cond.Broadcast()
+			return
+		}
+		if len(q.pending) < reporterQueueSize {
+			q.pending = append(q.pending, evt)
+			q.accepted++
+			q.cond.Broadcast()
+			return
+		}
+
+		switch q.policy {
+		case OverflowDropOldest:
+			q.dropLocked(q.pending[0], "queue full")
+			q.pending = q.pending[1:]
+		default:
+			q.cond.Wait()
+		}
+	}
+}
+
+func (q *reporterQueue) dropLocked(evt Event, reason string) {
+	q.dropped++
+	logrus.Warnf("event reporter %s: dropping event %s #%d: %s", q.name, evt.Kind, evt.Seq, reason)
+}
+
+// next blocks until an event is queued and removes it. It returns false once
+// the queue is closed and empty or abandoned.
+func (q *reporterQueue) next() (Event, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.abandoned {
+			return Event{}, false
+		}
+		if len(q.pending) > 0 {
+			evt := q.pending[0]
+			q.pending = q.pending[1:]
+			q.cond.Broadcast()
+			return evt, true
+		}
+		if q.spill != nil && q.spill.count > 0 {
+			evt, err := q.spill.read()
+			if err != nil {
+				logrus.Warnf("event reporter %s: read spilled events: %v", q.name, err)
+				q.dropped += uint64(q.spill.count)
+				q.spill.reset()
+				continue
+			}
+			return evt, true
+		}
+		if q.closed {
+			return Event{}, false
+		}
+		q.cond.Wait()
+	}
+}
+
+func (q *reporterQueue) run() {
+	defer close(q.done)
+	for {
+		evt, ok := q.next()
+		if !ok {
+			return
+		}
+		q.reporter.Report(evt)
+
+		q.mu.Lock()
+		q.delivered++
+		q.mu.Unlock()
+	}
+}
+
+func (q *reporterQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// abandon counts what is still queued as dropped and removes the spill file.
+func (q *reporterQueue) abandon() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.abandoned = true
+	lost := uint64(len(q.pending))
+	q.pending = nil
+	if q.spill != nil {
+		lost += uint64(q.spill.count)
+		q.spill.remove()
+	}
+	if lost > 0 {
+		q.dropped += lost
+		logrus.Warnf("event reporter %s: %d events not delivered within %s", q.name, lost, eventFlushTimeout)
+	}
+	q.cond.Broadcast()
+}
+
+func (q *reporterQueue) metrics() management.EventReporterMetrics {
+	q.mu.Lock()
+	m := management.EventReporterMetrics{
+		Name:      q.name,
+		Policy:    string(q.policy),
+		Accepted:  q.accepted,
+		Delivered: q.delivered,
+		Dropped:   q.dropped,
+		Spilled:   q.spilled,
+		Pending:   len(q.pending),
+	}
+	if q.spill != nil {
+		m.Pending += q.spill.count
+	}
+	q.mu.Unlock()
+
+	if fc, ok := q.reporter.(failureCounter); ok {
+		m.Failed = fc.failedEvents()
+	}
+	return m
+}
+
+// eventSpill is the overflow file of a reporterQueue: events are appended as
+// JSON lines and read back in order. The file is truncated whenever it has
+// been read completely. It is only used with the queue lock held.
+type eventSpill struct {
+	path  string
+	w     *os.File
+	r     *bufio.Reader
+	rf    *os.File
+	count int
+}
+
+func (s *eventSpill) write(evt Event) error {
+	if s.w == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+			return fmt.Errorf("create spill directory: %w", err)
+		}
+		w, err := os.OpenFile(s.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("open spill file: %w", err)
+		}
+		rf, err := os.Open(s.path)
+		if err != nil {
+			_ = w.Close()
+			return fmt.Errorf("open spill file: %w", err)
+		}
+		s.w, s.rf, s.r = w, rf, bufio.NewReader(rf)
+	}
+
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+	s.count++
+	return nil
+}
+
+func (s *eventSpill) read() (Event, error) {
+	line, err := s.r.ReadBytes('\n')
+	if err != nil {
+		return Event{}, err
+	}
+	var evt Event
+	if err := json.Unmarshal(line, &evt); err != nil {
+		return Event{}, err
+	}
+	s.count--
+	if s.count == 0 {
+		s.reset()
+	}
+	return evt, nil
+}
+
+// reset empties the file once every spilled event has been read.
+func (s *eventSpill) reset() {
+	s.count = 0
+	if s.w == nil {
+		return
+	}
+	if err := s.w.Truncate(0); err != nil {
+		logrus.Debugf("truncate spill file %q: %v", s.path, err)
+	}
+	if _, err := s.rf.Seek(0, io.SeekStart); err != nil {
+		logrus.Debugf("rewind spill file %q: %v", s.path, err)
+	}
+	s.r.Reset(s.rf)
+}
+
+func (s *eventSpill) remove() {
+	s.count = 0
+	if s.w == nil {
+		return
 	}
+	_ = s.w.Close()
+	_ = s.rf.Close()
+	s.w, s.rf, s.r = nil, nil, nil
+	_ = os.Remove(s.path)
 }
@@ -0,0 +1,96 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"context"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/network"
+	"linuxvm/pkg/service/management"
+	sshsvc "linuxvm/pkg/service/ssh"
+	"sync"
+	"time"
+)
+
+const healthProbeTimeout = 2 * time.Second
+
+// vmHealth records the components that report readiness once: the host
+// network stack and the guest agent's progress. SSH and podman are probed on
+// every health request because they can go away again.
+type vmHealth struct {
+	mu    sync.Mutex
+	ready map[string]bool
+}
+
+func (h *vmHealth) markReady(component string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ready == nil {
+		h.ready = map[string]bool{}
+	}
+	h.ready[component] = true
+}
+
+func (h *vmHealth) isReady(component string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ready[component]
+}
+
+func (h *vmHealth) component(name, waiting string) management.ComponentHealth {
+	if h.isReady(name) {
+		return management.ComponentHealth{Name: name, Ready: true}
+	}
+	return management.ComponentHealth{Name: name, Message: waiting}
+}
+
+func (m managementMachine) Health(ctx context.Context) management.HealthView {
+	components := []management.ComponentHealth{
+		m.health.component(management.HealthNetwork, "waiting for the host network stack"),
+		m.health.component(management.HealthGuestAgent, "waiting for the guest agent to fetch its config"),
+		m.health.component(management.HealthMounts, "waiting for the guest agent to mount disks and shared directories"),
+	}
+	// Probing before the guest agent runs would only wait for dial timeouts.
+	guestUp := m.health.isReady(management.HealthGuestAgent)
+
+	ssh := management.ComponentHealth{Name: management.HealthSSH, Message: "waiting for the guest agent"}
+	if guestUp {
+		ssh = probeSSH(ctx, m.SSHTarget())
+	}
+	components = append(components, ssh)
+
+	if m.RunMode() == define.ContainerMode.String() {
+		podman := management.ComponentHealth{Name: management.HealthPodman, Message: "waiting for the guest agent"}
+		if guestUp {
+			podman = probePodman(ctx, m.PodmanHostProxyAddr())
+		}
+		components = append(components, podman)
+	}
+	return management.NewHealthView(components)
+}
+
+func probeSSH(ctx context.Context, target sshsvc.Target) management.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	client, err := sshsvc.MakeSSHClient(ctx, target)
+	if err != nil {
+		return management.ComponentHealth{Name: management.HealthSSH, Message: "ssh connect: " + err.Error()}
+	}
+	_ = client.Close()
+	return management.ComponentHealth{Name: management.HealthSSH, Ready: true}
+}
+
+func probePodman(ctx context.Context, proxyAddr string) management.ComponentHealth {
+	addr, err := network.ParseUnixAddr(proxyAddr)
+	if err != nil {
+		return management.ComponentHealth{Name: management.HealthPodman, Message: "parse podman proxy address: " + err.Error()}
+	}
+	client := network.NewUnixClient(addr.Path, network.WithTimeout(healthProbeTimeout))
+	defer client.Close()
+
+	if !pingPodman(ctx, client) {
+		return management.ComponentHealth{Name: management.HealthPodman, Message: "podman API does not answer " + addr.Path}
+	}
+	return management.ComponentHealth{Name: management.HealthPodman, Ready: true}
+}
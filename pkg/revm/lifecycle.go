@@ -0,0 +1,137 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"linuxvm/pkg/service/management"
+	"sync"
+	"time"
+)
+
+// stateOrder ranks the lifecycle states; a VM only moves to a higher rank.
+var stateOrder = map[management.LifecycleState]int{
+	management.StateBuilding:     0,
+	management.StateBooting:      1,
+	management.StateNetworkReady: 2,
+	management.StateGuestReady:   3,
+	management.StateRunning:      4,
+	management.StateStopping:     5,
+	management.StateStopped:      6,
+	management.StateFailed:       6,
+}
+
+// vmLifecycle is the explicit lifecycle state of a VM and its history.
+type vmLifecycle struct {
+	mu          sync.Mutex
+	transitions []management.StateTransition
+}
+
+// transition moves to state and reports whether it did. Moving backwards,
+// for example to network_ready after the guest is ready, or leaving a final
+// state is ignored.
+func (l *vmLifecycle) transition(state management.LifecycleState, cause string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n := len(l.transitions); n > 0 {
+		current := l.transitions[n-1].State
+		if current == management.StateStopped || current == management.StateFailed {
+			return false
+		}
+		if stateOrder[state] <= stateOrder[current] {
+			return false
+		}
+	}
+	l.transitions = append(l.transitions, management.StateTransition{
+		State: state,
+		Time:  time.Now(),
+		Cause: cause,
+	})
+	return true
+}
+
+func (l *vmLifecycle) view() management.StateView {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.transitions) == 0 {
+		return management.StateView{}
+	}
+	last := l.transitions[len(l.transitions)-1]
+	view := management.StateView{
+		State:       last.State,
+		Transitions: make([]management.StateTransition, len(l.transitions)),
+	}
+	copy(view.Transitions, l.transitions)
+	view.Cause = last.Cause
+	return view
+}
+
+// State returns the lifecycle state of the VM and the time each state was
+// entered.
+func (vm *VM) State() management.StateView {
+	return vm.lifecycle.view()
+}
+
+// setState records a lifecycle transition and reports it as an
+// EventStateChanged event.
+func (vm *VM) setState(state management.LifecycleState, cause string) {
+	if !vm.lifecycle.transition(state, cause) {
+		return
+	}
+	msg := string(state)
+	if cause != "" {
+		msg += ": " + cause
+	}
+	vm.emitEvent(EventStateChanged, msg, state)
+}
+
+// vmStop records how the VM ends: the strongest stop requested so far and,
+// once the VM is gone, the outcome.
+type vmStop struct {
+	mu        sync.Mutex
+	requested management.StopOutcome
+	outcome   management.StopOutcome
+	done      chan struct{}
+	once      sync.Once
+}
+
+func (s *vmStop) init() {
+	s.once.Do(func() { s.done = make(chan struct{}) })
+}
+
+// request records a graceful or forced stop request; forced wins.
+func (s *vmStop) request(outcome management.StopOutcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requested != management.StopForced {
+		s.requested = outcome
+	}
+}
+
+// end records that the VM is gone. fallback is the outcome when no stop was
+// requested. Only the first call counts.
+func (s *vmStop) end(fallback management.StopOutcome) {
+	s.init()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome != "" {
+		return
+	}
+	s.outcome = s.requested
+	if s.outcome == "" {
+		s.outcome = fallback
+	}
+	close(s.done)
+}
+
+func (s *vmStop) doneChan() <-chan struct{} {
+	s.init()
+	return s.done
+}
+
+func (s *vmStop) result() management.StopOutcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outcome
+}
@@ -0,0 +1,288 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogSource selects which log stream of a session to read.
+type LogSource string
+
+const (
+	// LogSourceHost is the launcher log (logs/revm.log, or the file given with
+	// --log-to).
+	LogSourceHost LogSource = "host"
+	// LogSourceGuest is the guest-agent log received over the guest-log port (logs/vm.log).
+	LogSourceGuest LogSource = "guest"
+	// LogSourceAll merges the host and guest logs in timestamp order.
+	LogSourceAll LogSource = "all"
+)
+
+const logFollowInterval = 250 * time.Millisecond
+
+// LogOptions controls Logs.
+type LogOptions struct {
+	Source LogSource
+	// Since skips lines logged before this time; zero means no filter.
+	Since time.Time
+	// Follow keeps streaming new lines until ctx is done. A log file that is
+	// truncated or replaced, e.g. by the size limit applied when a session
+	// starts, is read again from its beginning.
+	Follow bool
+}
+
+// Logs writes the logs of sessionID to w. With LogSourceAll every line is
+// prefixed with its source.
+func Logs(ctx context.Context, sessionID string, opts LogOptions, w io.Writer) error {
+	if sessionID == "" {
+		return fmt.Errorf("session name must not be empty")
+	}
+
+	pathMgr := newMachinePathManager(getSessionDir(sessionID))
+	var streams []*logStream
+	switch opts.Source {
+	case LogSourceHost:
+		streams = []*logStream{{source: LogSourceHost, path: hostLogFile(pathMgr)}}
+	case LogSourceGuest:
+		streams = []*logStream{{source: LogSourceGuest, path: pathMgr.GetGuestLogFile()}}
+	case LogSourceAll, "":
+		streams = []*logStream{
+			{source: LogSourceHost, path: hostLogFile(pathMgr)},
+			{source: LogSourceGuest, path: pathMgr.GetGuestLogFile()},
+		}
+	default:
+		return fmt.Errorf("log source must be %q, %q or %q, got %q", LogSourceHost, LogSourceGuest, LogSourceAll, opts.Source)
+	}
+	defer func() {
+		for _, s := range streams {
+			s.close()
+		}
+	}()
+
+	prefix := len(streams) > 1
+	pump := func(final bool) error {
+		var lines []logLine
+		for _, s := range streams {
+			newLines, err := s.read(final)
+			if err != nil {
+				return err
+			}
+			lines = mergeLogLines(lines, newLines)
+		}
+		return writeLogLines(w, lines, opts.Since, prefix)
+	}
+
+	if !opts.Follow {
+		return pump(true)
+	}
+
+	if err := pump(false); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := pump(false); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// hostLogFile returns the host log of the session, following the path
+// recorded by a launch with a custom log file.
+func hostLogFile(pathMgr *machinePathManager) string {
+	data, err := os.ReadFile(pathMgr.GetHostLogPathFile())
+	if err == nil {
+		if path := strings.TrimSpace(string(data)); path != "" {
+			return path
+		}
+	}
+	return pathMgr.GetHostLogFile()
+}
+
+type logLine struct {
+	time   time.Time
+	source LogSource
+	text   string
+}
+
+// logStream reads one log file incrementally.
+type logStream struct {
+	source  LogSource
+	path    string
+	file    *os.File
+	offset  int64
+	partial []byte
+	// last is the timestamp of the latest line that carried one; lines
+	// without a timestamp, such as multi-line messages, inherit it.
+	last time.Time
+}
+
+// read returns the complete lines appended since the previous call. When final
+// is set, a trailing line without newline is returned as well.
+func (s *logStream) read(final bool) ([]logLine, error) {
+	if err := s.reopenIfReplaced(); err != nil {
+		return nil, err
+	}
+	if s.file == nil {
+		return nil, nil
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %q: %w", s.path, err)
+	}
+	if info.Size() < s.offset {
+		// Truncated in place: start over.
+		s.offset = 0
+		s.partial = nil
+	}
+
+	data := make([]byte, info.Size()-s.offset)
+	n, err := s.file.ReadAt(data, s.offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read %q: %w", s.path, err)
+	}
+	s.offset += int64(n)
+	data = append(s.partial, data[:n]...)
+	s.partial = nil
+
+	var lines []logLine
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			if !final {
+				s.partial = data
+				break
+			}
+			i = len(data)
+		}
+		lines = append(lines, s.parse(string(data[:i])))
+		if i == len(data) {
+			break
+		}
+		data = data[i+1:]
+	}
+	return lines, nil
+}
+
+// reopenIfReplaced opens the log file on first use and again whenever the
+// path points to a different file than the one being read.
+func (s *logStream) reopenIfReplaced() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("stat %q: %w", s.path, err)
+	}
+
+	if s.file != nil {
+		current, err := s.file.Stat()
+		if err == nil && os.SameFile(info, current) {
+			return nil
+		}
+		s.close()
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open %q: %w", s.path, err)
+	}
+	s.file = f
+	s.offset = 0
+	s.partial = nil
+	return nil
+}
+
+func (s *logStream) close() {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+}
+
+var (
+	// logrus text formatter with colors: "\x1b[36mINFO\x1b[0m[2006-01-02 15:04:05.000] msg"
+	logColorTimestamp = regexp.MustCompile(`^(?:\x1b\[[0-9;]*m)?[A-Z]+(?:\x1b\[0m)?\[([0-9-]+ [0-9:.]+)\]`)
+	// logrus text formatter without colors: `time="2006-01-02 15:04:05.000" level=info msg=...`
+	logPlainTimestamp = regexp.MustCompile(`^time="([^"]+)"`)
+)
+
+const logTimestampLayout = "2006-01-02 15:04:05.000"
+
+func (s *logStream) parse(text string) logLine {
+	var raw string
+	if m := logColorTimestamp.FindStringSubmatch(text); m != nil {
+		raw = m[1]
+	} else if m := logPlainTimestamp.FindStringSubmatch(text); m != nil {
+		raw = m[1]
+	}
+
+	if raw != "" {
+		if t, err := time.ParseInLocation(logTimestampLayout, raw, time.Local); err == nil {
+			s.last = t
+		} else if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			s.last = t
+		}
+	}
+	return logLine{time: s.last, source: s.source, text: text}
+}
+
+// mergeLogLines merges two streams that are each in log order. Lines of one
+// stream are never reordered, even if its clock went backwards.
+func mergeLogLines(a, b []logLine) []logLine {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+
+	merged := make([]logLine, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].time.Before(a[0].time) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+func writeLogLines(w io.Writer, lines []logLine, since time.Time, prefix bool) error {
+	for _, line := range lines {
+		if !since.IsZero() && line.time.Before(since) {
+			continue
+		}
+		var err error
+		if prefix {
+			_, err = fmt.Fprintf(w, "%-5s | %s\n", line.source, line.text)
+		} else {
+			_, err = fmt.Fprintln(w, line.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"linuxvm/pkg/define"
 	"linuxvm/pkg/filesystem"
+	"linuxvm/pkg/gvproxy"
 	"linuxvm/pkg/network"
 	ssh "linuxvm/pkg/ssh"
 	"linuxvm/pkg/static_resources"
@@ -71,10 +72,10 @@ func (v *machineBuilder) setupWorkspace(workspacePath string) error {
 }
 
 func (v *machineBuilder) lock() error {
-	// Lock file lives OUTSIDE the workspace so that the clean helper can
+	// Lock file lives OUTSIDE the workspace so that CleanSessions can
 	// acquire it after the workspace is deleted, preventing it from
 	// removing a workspace that belongs to a new session with the same name.
-	fileLock := flock.New(v.WorkspaceDir + ".lock")
+	fileLock := flock.New(v.WorkspaceDir + sessionLockSuffix)
 
 	locked, err := fileLock.TryLock()
 	if err != nil {
@@ -440,6 +441,7 @@ func (p *machineBuildPlan) build(ctx context.Context) error {
 		{"ssh", p.configureSSH},
 		{"resources", p.configureResources},
 		{"network", p.configureNetwork},
+		{"publish", p.configurePublish},
 		{"proxy", p.configureProxy},
 		{"rootfs", p.prepareRootfs},
 		{"mode", p.configureMode},
@@ -463,18 +465,15 @@ func (p *machineBuildPlan) setupWorkspace(ctx context.Context) error {
 	}
 	p.cleanupCallbacks.AddFunc(func() {
 		_ = p.builder.fileLock.Unlock()
-		_ = os.Remove(p.workspacePath + ".lock")
+		_ = os.Remove(p.workspacePath + sessionLockSuffix)
 	})
 	return nil
 }
 
 func (p *machineBuildPlan) configureLogFile(ctx context.Context) error {
-	// cfg.LogTo is set by WithLogging; fall back to workspace-relative path.
-	if p.cfg.LogTo != "" {
-		p.builder.LogFile = p.cfg.LogTo
-	} else {
-		p.builder.LogFile = filepath.Join(p.builder.WorkspaceDir, "logs", "vm.log")
-	}
+	// Guest logs stay in the workspace, apart from the host log in cfg.LogTo,
+	// so that they can be read back per source.
+	p.builder.LogFile = p.builder.pathMgr.GetGuestLogFile()
 	return nil
 }
 
@@ -490,6 +489,21 @@ func (p *machineBuildPlan) configureNetwork(ctx context.Context) error {
 	return p.builder.configureNetwork(ctx, define.VNetMode(p.cfg.Network))
 }
 
+func (p *machineBuildPlan) configurePublish(ctx context.Context) error {
+	if len(p.cfg.Publish) == 0 {
+		return nil
+	}
+	mappings, err := parsePublishSpecs(p.cfg.Publish)
+	if err != nil {
+		return err
+	}
+	if err := gvproxy.ValidatePortForwards(p.builder.SSHInfo.HostSSHProxyListenAddr, mappings); err != nil {
+		return err
+	}
+	p.builder.PortForwards = mappings
+	return nil
+}
+
 func (p *machineBuildPlan) configureProxy(ctx context.Context) error {
 	if !p.cfg.Proxy {
 		return nil
@@ -595,12 +609,18 @@ func (v *machineBuilder) detectTTY() {
 		term.IsTerminal(int(os.Stderr.Fd()))
 }
 
-func getSessionDir(name string) string {
+// getSessionRoot returns the directory that holds every session workspace
+// and its sibling lock file.
+func getSessionRoot() string {
 	dir, err := os.UserHomeDir()
 	if err != nil {
-		return filepath.Join("/tmp", "revm", name)
+		return filepath.Join("/tmp", "revm")
 	}
-	return filepath.Join(dir, ".cache", "revm", name)
+	return filepath.Join(dir, ".cache", "revm")
+}
+
+func getSessionDir(name string) string {
+	return filepath.Join(getSessionRoot(), name)
 }
 
 func ignitionSockFile(workspace string) string {
@@ -50,6 +50,33 @@ func (p *machinePathManager) GetLogsDir() string {
 	return filepath.Join(p.workspaceDir, "logs")
 }
 
+// GetHostLogFile returns the default log file of the launcher process.
+func (p *machinePathManager) GetHostLogFile() string {
+	return filepath.Join(p.GetLogsDir(), "revm.log")
+}
+
+// GetHostLogPathFile records the host log file of a session started with a
+// custom log path, so that Logs can find it.
+func (p *machinePathManager) GetHostLogPathFile() string {
+	return filepath.Join(p.GetLogsDir(), "revm.log.path")
+}
+
+// GetGuestLogFile returns the file fed by the guest-log virtio console port.
+func (p *machinePathManager) GetGuestLogFile() string {
+	return filepath.Join(p.GetLogsDir(), "vm.log")
+}
+
+// GetEventJournalFile returns the JSONL journal of every event of the session,
+// rotated to events.jsonl.1, .2 and so on.
+func (p *machinePathManager) GetEventJournalFile() string {
+	return filepath.Join(p.GetLogsDir(), "events.jsonl")
+}
+
+// GetEventSpillDir holds the overflow files of spill-to-disk event reporters.
+func (p *machinePathManager) GetEventSpillDir() string {
+	return filepath.Join(p.workspaceDir, "events")
+}
+
 func (p *machinePathManager) GetRootfsDir() string {
 	return filepath.Join(p.workspaceDir, "rootfs")
 }
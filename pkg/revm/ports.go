@@ -0,0 +1,220 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"context"
+	"fmt"
+	runtimemachine "linuxvm/internal/machine"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/gvproxy"
+	"linuxvm/pkg/network"
+	"linuxvm/pkg/protocol"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ExposePort publishes a guest port on the host while the VM runs. The
+// forward goes away when the VM stops. It requires the gvisor network mode.
+// Unix socket forwards only admit the local users of the peer allowlist.
+func (vm *VM) ExposePort(ctx context.Context, fwd protocol.PortForward) error {
+	return vm.ports.expose(ctx, vm.runtime.view, fwd)
+}
+
+// UnexposePort removes a forward added by ExposePort. Only fwd.Protocol and
+// fwd.Host are used.
+func (vm *VM) UnexposePort(ctx context.Context, fwd protocol.PortForward) error {
+	return vm.ports.unexpose(ctx, vm.runtime.view, fwd)
+}
+
+// Ports lists the active port forwards, including the SSH forward.
+func (vm *VM) Ports(ctx context.Context) ([]protocol.PortForward, error) {
+	return vm.ports.list(ctx, vm.runtime.view)
+}
+
+// portForwards tracks the unix socket forwards added at runtime. gvproxy could
+// listen on these sockets itself, but it does not check the peer credentials,
+// so they are served by a tunnel with the peer allowlist instead, like the
+// Podman API socket. TCP and UDP forwards are left to gvproxy.
+type portForwards struct {
+	peers network.PeerAllowlist
+
+	mu   sync.Mutex
+	unix map[string]*unixForward
+}
+
+type unixForward struct {
+	fwd    protocol.PortForward
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (p *portForwards) expose(ctx context.Context, view *runtimemachine.Machine, fwd protocol.PortForward) error {
+	if fwd.Protocol != protocol.PortProtocolUnix {
+		return exposePort(ctx, view, fwd)
+	}
+	fwd, err := fwd.Normalize()
+	if err != nil {
+		return err
+	}
+	if fwd.GuestPort == 0 {
+		return fmt.Errorf("guest port must not be empty")
+	}
+	if view.VirtualNetworkMode() != define.GVISOR {
+		return fmt.Errorf("port forwarding requires the %s network mode, this VM uses %s", define.GVISOR, view.VirtualNetworkMode())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.unix[fwd.Host]; ok {
+		return fmt.Errorf("%s is already forwarded", fwd.Host)
+	}
+
+	tunnel, err := gvproxy.ListenUnixTunnel(view.GVPCtlAddr(), "unix://"+fwd.Host, define.GuestIP, fwd.GuestPort, p.peers)
+	if err != nil {
+		return err
+	}
+	serveCtx, cancel := context.WithCancel(context.Background())
+	forward := &unixForward{fwd: fwd, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(forward.done)
+		if err := tunnel.Serve(serveCtx); err != nil && serveCtx.Err() == nil {
+			logrus.Warnf("unix forward %s: %v", fwd.Host, err)
+		}
+	}()
+
+	if p.unix == nil {
+		p.unix = make(map[string]*unixForward)
+	}
+	p.unix[fwd.Host] = forward
+	return nil
+}
+
+func (p *portForwards) unexpose(ctx context.Context, view *runtimemachine.Machine, fwd protocol.PortForward) error {
+	if fwd.Protocol != protocol.PortProtocolUnix {
+		return unexposePort(ctx, view, fwd)
+	}
+	fwd, err := fwd.Normalize()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	forward, ok := p.unix[fwd.Host]
+	delete(p.unix, fwd.Host)
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no unix forward on %s", fwd.Host)
+	}
+	forward.cancel()
+	<-forward.done
+	return nil
+}
+
+func (p *portForwards) list(ctx context.Context, view *runtimemachine.Machine) ([]protocol.PortForward, error) {
+	ports, err := listPorts(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, forward := range p.unix {
+		ports = append(ports, forward.fwd)
+	}
+	return ports, nil
+}
+
+// close removes every unix forward; the VM is gone.
+func (p *portForwards) close() {
+	p.mu.Lock()
+	forwards := p.unix
+	p.unix = nil
+	p.mu.Unlock()
+
+	for _, forward := range forwards {
+		forward.cancel()
+		<-forward.done
+	}
+}
+
+func newForwarderClient(view *runtimemachine.Machine) (*gvproxy.ForwarderClient, error) {
+	if view.VirtualNetworkMode() != define.GVISOR {
+		return nil, fmt.Errorf("port forwarding requires the %s network mode, this VM uses %s", define.GVISOR, view.VirtualNetworkMode())
+	}
+	return gvproxy.NewForwarderClient(view.GVPCtlAddr())
+}
+
+func exposePort(ctx context.Context, view *runtimemachine.Machine, fwd protocol.PortForward) error {
+	fwd, err := fwd.Normalize()
+	if err != nil {
+		return err
+	}
+	if fwd.GuestPort == 0 {
+		return fmt.Errorf("guest port must not be empty")
+	}
+
+	client, err := newForwarderClient(view)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Expose(ctx, types.ExposeRequest{
+		Local:    fwd.Host,
+		Remote:   net.JoinHostPort(define.GuestIP, strconv.Itoa(int(fwd.GuestPort))),
+		Protocol: types.TransportProtocol(fwd.Protocol),
+	})
+}
+
+func unexposePort(ctx context.Context, view *runtimemachine.Machine, fwd protocol.PortForward) error {
+	fwd, err := fwd.Normalize()
+	if err != nil {
+		return err
+	}
+	if fwd.Protocol == protocol.PortProtocolTCP && fwd.Host == view.GVProxySpec().HostSSHForwardAddr {
+		return fmt.Errorf("the SSH forward on %s can not be removed", fwd.Host)
+	}
+
+	client, err := newForwarderClient(view)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Unexpose(ctx, types.UnexposeRequest{
+		Local:    fwd.Host,
+		Protocol: types.TransportProtocol(fwd.Protocol),
+	})
+}
+
+func listPorts(ctx context.Context, view *runtimemachine.Machine) ([]protocol.PortForward, error) {
+	client, err := newForwarderClient(view)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	forwards, err := client.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]protocol.PortForward, 0, len(forwards))
+	for _, f := range forwards {
+		fwd := protocol.PortForward{Protocol: string(f.Protocol), Host: f.Local}
+		if fwd.Protocol == "" {
+			fwd.Protocol = protocol.PortProtocolTCP
+		}
+		if _, port, err := net.SplitHostPort(f.Remote); err == nil {
+			if n, err := strconv.ParseUint(port, 10, 16); err == nil {
+				fwd.GuestPort = uint16(n)
+			}
+		}
+		ports = append(ports, fwd)
+	}
+	return ports, nil
+}
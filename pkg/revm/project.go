@@ -0,0 +1,101 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectConfigFileNames lists the project-local config files looked up by
+// DiscoverConfig, in order of preference within a directory.
+var ProjectConfigFileNames = []string{".revm.json", ".revm.yaml", ".revm.yml"}
+
+// DiscoverConfig walks up from dir to the filesystem root and returns the first
+// project-local config file found, or an empty string if there is none.
+func DiscoverConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve %q: %w", dir, err)
+	}
+
+	for {
+		for _, name := range ProjectConfigFileNames {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err == nil && info.Mode().IsRegular() {
+				return path, nil
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("stat %q: %w", path, err)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// ProjectSessionID derives a stable session name from a project directory: the
+// directory name followed by a short hash of its absolute path, so that two
+// checkouts with the same name do not share a session.
+func ProjectSessionID(projectDir string) string {
+	if abs, err := filepath.Abs(projectDir); err == nil {
+		projectDir = abs
+	}
+	sum := sha256.Sum256([]byte(projectDir))
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, filepath.Base(projectDir))
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		name = "project"
+	}
+
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// resolvePaths makes the relative host paths of c absolute against baseDir.
+// Guest paths (mount targets, workdir, disk mount points) are left untouched.
+func (c *Config) resolvePaths(baseDir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	c.Rootfs = resolve(c.Rootfs)
+	c.PodmanProxyAPIFile = resolve(c.PodmanProxyAPIFile)
+	c.ManageAPIFile = resolve(c.ManageAPIFile)
+	c.SSHKeyFileSymbolPath = resolve(c.SSHKeyFileSymbolPath)
+	c.LogTo = resolve(c.LogTo)
+
+	for i, spec := range c.Mounts {
+		// "/host:/guest[,ro]" or "/path[,ro]"; the source ends at the first ':' or ','.
+		end := strings.IndexAny(spec, ":,")
+		if end < 0 {
+			end = len(spec)
+		}
+		c.Mounts[i] = resolve(spec[:end]) + spec[end:]
+	}
+	for i := range c.Disks {
+		c.Disks[i].Path = resolve(c.Disks[i].Path)
+	}
+	if c.ContainerDisk != nil {
+		c.ContainerDisk.Path = resolve(c.ContainerDisk.Path)
+	}
+}
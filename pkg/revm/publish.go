@@ -0,0 +1,99 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"fmt"
+	"linuxvm/pkg/define"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// WithPublish publishes guest ports on the host from boot. Each spec has the
+// form [hostIP:]hostPort:guestPort[/tcp|/udp]; the host IP defaults to
+// 127.0.0.1 and the protocol to tcp. It requires the gvisor network.
+func (c *Config) WithPublish(specs ...string) *Config {
+	for _, spec := range specs {
+		if spec == "" {
+			continue
+		}
+		c.Publish = append(c.Publish, spec)
+	}
+	return c
+}
+
+// parsePublishSpecs parses --publish specs and rejects two specs that bind
+// the same host address.
+func parsePublishSpecs(specs []string) ([]define.PortMapping, error) {
+	mappings := make([]define.PortMapping, 0, len(specs))
+	for _, spec := range specs {
+		m, err := parsePublishSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, prev := range mappings {
+			if portMappingsOverlap(prev, m) {
+				return nil, fmt.Errorf("publish %q: host port %d/%s is already published", spec, m.HostPort, m.Protocol)
+			}
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+func parsePublishSpec(spec string) (define.PortMapping, error) {
+	m := define.PortMapping{HostIP: define.LocalHost, Protocol: "tcp"}
+
+	rest := spec
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		m.Protocol = strings.ToLower(rest[i+1:])
+		rest = rest[:i]
+		if m.Protocol != "tcp" && m.Protocol != "udp" {
+			return define.PortMapping{}, fmt.Errorf("publish %q: protocol must be tcp or udp, got %q", spec, m.Protocol)
+		}
+	}
+
+	i := strings.LastIndex(rest, ":")
+	if i < 0 {
+		return define.PortMapping{}, fmt.Errorf("publish %q: want [hostIP:]hostPort:guestPort[/tcp|/udp]", spec)
+	}
+	guestPort, err := parsePort(rest[i+1:])
+	if err != nil {
+		return define.PortMapping{}, fmt.Errorf("publish %q: invalid guest port: %w", spec, err)
+	}
+	m.GuestPort = guestPort
+	rest = rest[:i]
+
+	hostPort := rest
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		hostIP := strings.TrimSuffix(strings.TrimPrefix(rest[:i], "["), "]")
+		if net.ParseIP(hostIP) == nil {
+			return define.PortMapping{}, fmt.Errorf("publish %q: invalid host IP %q", spec, hostIP)
+		}
+		m.HostIP = hostIP
+		hostPort = rest[i+1:]
+	}
+	if m.HostPort, err = parsePort(hostPort); err != nil {
+		return define.PortMapping{}, fmt.Errorf("publish %q: invalid host port: %w", spec, err)
+	}
+	return m, nil
+}
+
+func parsePort(s string) (uint16, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("%q is not a port between 1 and 65535", s)
+	}
+	return uint16(port), nil
+}
+
+// portMappingsOverlap reports whether a and b would listen on the same host
+// socket. An unspecified host IP overlaps every address.
+func portMappingsOverlap(a, b define.PortMapping) bool {
+	if a.Protocol != b.Protocol || a.HostPort != b.HostPort {
+		return false
+	}
+	ipA, ipB := net.ParseIP(a.HostIP), net.ParseIP(b.HostIP)
+	return ipA.IsUnspecified() || ipB.IsUnspecified() || ipA.Equal(ipB)
+}
@@ -0,0 +1,158 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"context"
+	"fmt"
+	"linuxvm/pkg/client"
+	"linuxvm/pkg/service/management"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const sessionLockSuffix = ".lock"
+
+// SessionState describes whether a session workspace is owned by a live launcher.
+type SessionState string
+
+const (
+	// SessionRunning means a launcher currently holds the session lock.
+	SessionRunning SessionState = "running"
+	// SessionStale means nothing holds the session lock; the workspace is left over.
+	SessionStale SessionState = "stale"
+)
+
+// SessionInfo describes one session found under the session root.
+type SessionInfo struct {
+	ID        string       `json:"id"`
+	State     SessionState `json:"state"`
+	Workspace string       `json:"workspace"`
+
+	// VMConfig is queried from the management API of running sessions.
+	VMConfig *management.VMConfigView `json:"vmconfig,omitempty"`
+	// Error records why a running session could not be queried.
+	Error string `json:"error,omitempty"`
+}
+
+// Uptime returns how long the session VM has been running, or zero when unknown.
+func (s SessionInfo) Uptime() time.Duration {
+	if s.VMConfig == nil || s.VMConfig.StartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.VMConfig.StartedAt)
+}
+
+// ListSessions walks the session root and reports every session workspace or
+// lock file found there. Running sessions are queried over their management API.
+func ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	ids, err := sessionIDs(getSessionRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]SessionInfo, 0, len(ids))
+	for _, id := range ids {
+		info, err := inspectSession(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("inspect session %q: %w", id, err)
+		}
+		sessions = append(sessions, info)
+	}
+	return sessions, nil
+}
+
+// sessionIDs returns the sorted set of session names that own either a
+// workspace directory or a lock file under root.
+func sessionIDs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read session root %q: %w", root, err)
+	}
+
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+		case strings.HasSuffix(name, sessionLockSuffix):
+			name = strings.TrimSuffix(name, sessionLockSuffix)
+		default:
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func inspectSession(ctx context.Context, id string) (SessionInfo, error) {
+	workspace := getSessionDir(id)
+	info := SessionInfo{
+		ID:        id,
+		State:     SessionStale,
+		Workspace: workspace,
+	}
+
+	running, err := isSessionLocked(workspace)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	if !running {
+		return info, nil
+	}
+
+	info.State = SessionRunning
+	view, err := fetchVMConfig(ctx, workspace)
+	if err != nil {
+		info.Error = err.Error()
+		return info, nil
+	}
+	info.VMConfig = &view
+	return info, nil
+}
+
+// isSessionLocked reports whether a launcher holds the lock of the session
+// workspace. A missing lock file means no launcher owns the session.
+func isSessionLocked(workspace string) (bool, error) {
+	lockPath := workspace + sessionLockSuffix
+	if _, err := os.Stat(lockPath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stat lock file %q: %w", lockPath, err)
+	}
+
+	fileLock := flock.New(lockPath)
+	locked, err := fileLock.TryRLock()
+	if err != nil {
+		return false, fmt.Errorf("probe lock %q: %w", lockPath, err)
+	}
+	if locked {
+		_ = fileLock.Unlock()
+		return false, nil
+	}
+	return true, nil
+}
+
+func fetchVMConfig(ctx context.Context, workspaceDirPath string) (management.VMConfigView, error) {
+	c := client.New(newMachinePathManager(workspaceDirPath).GetVMCtlSocketFile())
+	defer c.Close()
+
+	return c.VMConfig(ctx)
+}
@@ -0,0 +1,127 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"linuxvm/pkg/client"
+	"linuxvm/pkg/service/management"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultStopTimeout   = 30 * time.Second
+	stopForceGracePeriod = 10 * time.Second
+	stopPollInterval     = 200 * time.Millisecond
+)
+
+// StopOptions controls how Stop shuts a session down.
+type StopOptions struct {
+	// Timeout bounds the wait for a graceful guest shutdown before Stop
+	// escalates to a force stop; zero means 30s.
+	Timeout time.Duration
+	// Force skips the graceful shutdown request.
+	Force bool
+}
+
+// Stop asks the launcher that owns sessionID to shut the VM down through the
+// management API and waits until it releases the session lock, which only
+// happens once the launcher has exited. If the guest does not shut down within
+// opts.Timeout, the launcher escalates to a force stop. Stop reports how the
+// VM ended.
+func Stop(ctx context.Context, sessionID string, opts StopOptions) (management.StopOutcome, error) {
+	if sessionID == "" {
+		return "", fmt.Errorf("session name must not be empty")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultStopTimeout
+	}
+
+	workspace := getSessionDir(sessionID)
+	running, err := isSessionLocked(workspace)
+	if err != nil {
+		return "", err
+	}
+	if !running {
+		return "", fmt.Errorf("session %q is not running", sessionID)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.Timeout+stopForceGracePeriod)
+	defer cancel()
+	result, err := requestStop(waitCtx, workspace, client.StopOptions{Force: opts.Force, Timeout: opts.Timeout, Wait: true})
+	if err != nil && (ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+		return "", err
+	}
+
+	if !result.Stopped && err == nil {
+		// Launchers that predate the timeout and wait parameters answer at
+		// once and leave the escalation to us.
+		released, err := waitSessionReleased(ctx, workspace, opts.Timeout)
+		if err != nil {
+			return "", err
+		}
+		result.Stopped = released
+	}
+	if !result.Stopped {
+		logrus.Warnf("session %q did not stop within %s, forcing stop", sessionID, opts.Timeout)
+		if _, err := requestStop(ctx, workspace, client.StopOptions{Force: true}); err != nil {
+			return "", err
+		}
+		result.Outcome = management.StopForced
+	}
+
+	released, err := waitSessionReleased(ctx, workspace, stopForceGracePeriod)
+	if err != nil {
+		return "", err
+	}
+	if !released {
+		return "", fmt.Errorf("session %q is still locked %s after it stopped", sessionID, stopForceGracePeriod)
+	}
+	return result.Outcome, nil
+}
+
+func requestStop(ctx context.Context, workspace string, opts client.StopOptions) (management.StopResult, error) {
+	c := client.New(newMachinePathManager(workspace).GetVMCtlSocketFile())
+	defer c.Close()
+
+	result, err := c.Stop(ctx, opts)
+	var apiErr *client.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		// The launcher may already be tearing down its management API.
+		if locked, lockErr := isSessionLocked(workspace); lockErr == nil && !locked {
+			return management.StopResult{Stopped: true}, nil
+		}
+	}
+	return result, err
+}
+
+// waitSessionReleased polls the session lock until no launcher holds it or
+// timeout expires. It reports whether the lock was released.
+func waitSessionReleased(ctx context.Context, workspace string, timeout time.Duration) (bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(stopPollInterval)
+	defer ticker.Stop()
+
+	for {
+		locked, err := isSessionLocked(workspace)
+		if err != nil {
+			return false, err
+		}
+		if !locked {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timer.C:
+			return false, nil
+		case <-ticker.C:
+		}
+	}
+}
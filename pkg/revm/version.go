@@ -0,0 +1,47 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package revm
+
+import (
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/libkrun"
+	"linuxvm/pkg/protocol"
+	"runtime"
+)
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// versionInfo describes this build for /v2/version and the guest agent.
+func versionInfo() protocol.VersionInfo {
+	return protocol.VersionInfo{
+		Version:   orUnknown(define.Version),
+		Commit:    orUnknown(define.CommitID),
+		BuildDate: orUnknown(define.BuildDate),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Schemas: protocol.SchemaVersions{
+			AttachSpec:   protocol.AttachSpecRange,
+			GuestSpec:    protocol.GuestSpecRange,
+			ExecProtocol: protocol.ExecProtocolRange,
+		},
+		Libkrun: protocol.LibkrunInfo{
+			AssetsRelease: orUnknown(define.AssetsRelease),
+			Features:      libkrun.Features(),
+			MaxVCPUs:      libkrun.MaxVCPUs(),
+		},
+		Features: protocol.FeatureSupport{
+			NetworkModes: []string{string(define.GVISOR), string(define.TSI)},
+			RunModes:     []string{string(ModeRootfs), string(ModeContainer), string(ModeAttach)},
+		},
+	}
+}
+
+// Version describes the build serving the management API.
+func (m managementMachine) Version() protocol.VersionInfo {
+	return versionInfo()
+}
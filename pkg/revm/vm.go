@@ -13,8 +13,10 @@ import (
 	"linuxvm/pkg/gvproxy"
 	"linuxvm/pkg/libkrun"
 	"linuxvm/pkg/network"
+	"linuxvm/pkg/protocol"
 	"linuxvm/pkg/service/ignition"
 	"linuxvm/pkg/service/management"
+	ssev2 "linuxvm/pkg/sse"
 	"net"
 	"net/http"
 	"os"
@@ -49,8 +51,13 @@ type VM struct {
 	runtime       vmRuntime
 	workspace     vmWorkspace
 	observability vmObservability
+	health        vmHealth
+	lifecycle     vmLifecycle
+	stop          vmStop
+	ports         portForwards
 
-	seq atomic.Uint64
+	seq       atomic.Uint64
+	startedAt time.Time
 }
 
 type vmRuntime struct {
@@ -65,6 +72,7 @@ type vmWorkspace struct {
 
 type vmObservability struct {
 	events eventDispatcher
+	stream *streamEventReporter
 	runLog *os.File
 }
 
@@ -94,29 +102,21 @@ func (vm *VM) Release() error {
 		_ = vm.observability.runLog.Close()
 		vm.observability.runLog = nil
 	}
+	vm.observability.events.close()
+	// The backend may not return if the VMM outlived a force stop; the
+	// workspace lock must stay held until it is gone.
+	if vm.runtime.backend != nil {
+		vm.runtime.backend.Release()
+	}
 	if vm.workspace.release != nil {
 		vm.workspace.release()
 		vm.workspace.release = nil
 	}
-	vm.observability.events.close()
 	return nil
 }
 
 func buildTimeInfo() string {
-	version := define.Version
-	if version == "" {
-		version = "unknown"
-	}
-	commit := define.CommitID
-	if commit == "" {
-		commit = "unknown"
-	}
-	buildDate := define.BuildDate
-	if buildDate == "" {
-		buildDate = "unknown"
-	}
-
-	return fmt.Sprintf("%s-%s-%s", version, commit, buildDate)
+	return fmt.Sprintf("%s-%s-%s", orUnknown(define.Version), orUnknown(define.CommitID), orUnknown(define.BuildDate))
 }
 
 // Build resolves configuration defaults and acquires the heavyweight resources
@@ -153,11 +153,29 @@ func Build(ctx context.Context, cfg *Config) (*VM, error) {
 		observability: vmObservability{
 			runLog: logFile,
 		},
+		ports: portForwards{peers: normalizedCfg.peerAllowlist()},
+	}
+
+	pathMgr := newMachinePathManager(vm.workspace.dir)
+	vm.observability.events.spillDir = pathMgr.GetEventSpillDir()
+	vm.observability.stream = newStreamEventReporter()
+	vm.observability.events.addReporter(streamReporterName, vm.observability.stream, OverflowBlock)
+	if journal, err := newJournalEventReporter(pathMgr.GetEventJournalFile()); err != nil {
+		logrus.Warnf("event journal disabled: %v", err)
+	} else {
+		vm.observability.events.addReporter(journalReporterName, journal, OverflowBlock)
+	}
+	for _, spec := range normalizedCfg.reportURLs() {
+		// NormalizeConfig has validated the specs.
+		target, _ := parseReportTarget(spec)
+		if reporter := target.newReporter(); reporter != nil {
+			vm.observability.events.addReporter(target.name(), reporter, target.overflow)
+		}
 	}
-
-	if reporter := newEventReporter(normalizedCfg.ReportURL); reporter != nil {
-		vm.observability.events.addReporter(reporter)
+	for i, reporter := range normalizedCfg.reporters {
+		vm.observability.events.addReporter(fmt.Sprintf("in-process #%d", i+1), reporter, OverflowBlock)
 	}
+	vm.setState(management.StateBuilding, "")
 
 	if err := vm.build(ctx); err != nil {
 		_ = vm.Release()
@@ -167,6 +185,30 @@ func Build(ctx context.Context, cfg *Config) (*VM, error) {
 	return vm, nil
 }
 
+// recordHostLogFile stores a custom host log path in the workspace for Logs,
+// or removes a path left by an earlier launch that used one.
+func recordHostLogFile(pathMgr *machinePathManager, logTo string) error {
+	pathFile := pathMgr.GetHostLogPathFile()
+	if logTo == "" {
+		if err := os.Remove(pathFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove host log path: %w", err)
+		}
+		return nil
+	}
+
+	abs, err := filepath.Abs(logTo)
+	if err != nil {
+		return fmt.Errorf("resolve host log path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(pathFile), 0755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+	if err := os.WriteFile(pathFile, []byte(abs+"\n"), 0644); err != nil {
+		return fmt.Errorf("record host log path: %w", err)
+	}
+	return nil
+}
+
 func setupLogrus(level string) {
 	if level == "" {
 		level = logrus.InfoLevel.String()
@@ -190,7 +232,7 @@ func setupLogrus(level string) {
 func setupLogFile(cfg Config) (*os.File, error) {
 	logFilePath := cfg.LogTo
 	if logFilePath == "" {
-		logFilePath = filepath.Join(getSessionDir(cfg.SessionID), "logs", "revm.log")
+		logFilePath = newMachinePathManager(getSessionDir(cfg.SessionID)).GetHostLogFile()
 	}
 
 	if err := os.MkdirAll(filepath.Dir(logFilePath), 0755); err != nil {
@@ -217,6 +259,12 @@ func (vm *VM) build(ctx context.Context) error {
 		return fmt.Errorf("build machine: %w", err)
 	}
 
+	// Recorded only now that the workspace is locked by this launch.
+	if err := recordHostLogFile(newMachinePathManager(vm.workspace.dir), vm.cfg.LogTo); err != nil {
+		releaseWorkspace()
+		return err
+	}
+
 	if err := vm.createUserSymlinks(); err != nil {
 		releaseWorkspace()
 		return fmt.Errorf("create symlinks: %w", err)
@@ -300,6 +348,9 @@ func (vm *VM) createUserSymlinks() error {
 // the VM run to end. If the VM exits or the run is cancelled normally, Run
 // returns nil; otherwise it returns the first meaningful failure cause.
 func (vm *VM) Run(ctx context.Context) error {
+	vm.startedAt = time.Now()
+	vm.setState(management.StateBooting, "")
+
 	hostServicesCtx, stopHostServices := context.WithCancelCause(ctx)
 	defer stopHostServices(context.Canceled)
 
@@ -307,9 +358,11 @@ func (vm *VM) Run(ctx context.Context) error {
 	defer abortVMWait(context.Canceled)
 
 	finishVMRun := func(cause error) {
+		vm.stop.end(management.StopGuest)
 		stopHostServices(cause)
 	}
 	forceVMRun := func(cause error) {
+		vm.stop.end(management.StopForced)
 		abortVMWait(cause)
 		stopHostServices(cause)
 	}
@@ -322,17 +375,20 @@ func (vm *VM) Run(ctx context.Context) error {
 	vm.startHostService(&g, hostServicesCtx, forceVMRun, func(ctx context.Context) error {
 		return vm.startHostNetworkStack(ctx, signalNetworkReady)
 	})
-	vm.startHostService(&g, hostServicesCtx, forceVMRun, vm.startMachineManagementAPI)
-
-	if err := vm.startModeServices(&g, hostServicesCtx, forceVMRun, networkReady); err != nil {
-		return err
-	}
 
 	forceHostShutdown := func() {
 		vm.forceVirtualMachine()
 		forceVMRun(context.Canceled)
 	}
 
+	vm.startHostService(&g, hostServicesCtx, forceVMRun, func(ctx context.Context) error {
+		return vm.startMachineManagementAPI(ctx, forceHostShutdown)
+	})
+
+	if err := vm.startModeServices(&g, hostServicesCtx, forceVMRun, networkReady); err != nil {
+		return err
+	}
+
 	vm.startShutdownMonitors(hostServicesCtx, vm.requestGuestShutdown, forceHostShutdown)
 
 	g.Go(func() error {
@@ -345,6 +401,9 @@ func (vm *VM) Run(ctx context.Context) error {
 		vm.emit(EventVirtualMachineBooting, reason.Error())
 
 		err := vm.runtime.backend.Start(vmWaitAbortCtx)
+		if errors.Is(err, backend.ErrForceStopped) {
+			err = nil
+		}
 		if err != nil {
 			finishVMRun(err)
 		} else {
@@ -353,19 +412,34 @@ func (vm *VM) Run(ctx context.Context) error {
 		return err
 	})
 
-	return runError(hostServicesCtx, g.Wait())
+	err := runError(hostServicesCtx, g.Wait())
+	vm.ports.close()
+	if err != nil {
+		vm.setState(management.StateFailed, err.Error())
+	} else {
+		vm.setState(management.StateStopped, string(vm.stop.result()))
+	}
+	return err
 }
 
 func (vm *VM) requestGuestShutdown() {
-	if err := vm.runtime.backend.RequestShutdown(context.Background()); err != nil {
+	if err := vm.shutdownGuest(context.Background()); err != nil {
 		logrus.Warnf("request guest shutdown failed: %v", err)
 	}
 }
 
+// shutdownGuest asks the guest to shut down; the VM then ends gracefully
+// unless it is forced before the guest is done.
+func (vm *VM) shutdownGuest(ctx context.Context) error {
+	vm.stop.request(management.StopGraceful)
+	return vm.runtime.backend.RequestShutdown(ctx)
+}
+
 func (vm *VM) forceVirtualMachine() {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultForceStopTimeout)
 	defer cancel()
 
+	vm.stop.request(management.StopForced)
 	if err := vm.runtime.backend.ForceStop(ctx); err != nil {
 		logrus.Warnf("force stop virtual machine failed: %v", err)
 	}
@@ -405,6 +479,8 @@ func (vm *VM) newNetworkReadySignal() (<-chan struct{}, func()) {
 	return networkReady, func() {
 		once.Do(func() {
 			close(networkReady)
+			vm.health.markReady(management.HealthNetwork)
+			vm.setState(management.StateNetworkReady, "")
 			vm.emit(EventNetworkReady, "host network ready")
 		})
 	}
@@ -428,6 +504,7 @@ func (vm *VM) startHostService(g *errgroup.Group, ctx context.Context, forceVMRu
 			return err
 		}
 
+		vm.setState(management.StateFailed, err.Error())
 		vm.forceVirtualMachine()
 		forceVMRun(err)
 		return err
@@ -467,7 +544,8 @@ func (vm *VM) startPodmanProxy(ctx context.Context) error {
 			vm.runtime.view.GVPCtlAddr(),
 			vm.runtime.view.PodmanHostProxyAddr(),
 			define.GuestIP,
-			uint16(port))
+			uint16(port),
+			vm.cfg.peerAllowlist())
 	default:
 		return fmt.Errorf("podman proxy requires %s network, got %s", define.GVISOR, vm.runtime.view.VirtualNetworkMode())
 	}
@@ -488,14 +566,39 @@ func (vm *VM) startIgnitionService(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("create ignition server: %w", err)
 	}
+	server.Version = versionInfo()
+	server.OnGuestSpec = func() {
+		vm.health.markReady(management.HealthGuestAgent)
+		vm.setState(management.StateGuestReady, "")
+	}
+	server.OnMountsReady = func() {
+		vm.health.markReady(management.HealthMounts)
+		vm.setState(management.StateRunning, "")
+		vm.emitGuestEvent(protocol.GuestEvent{Kind: protocol.GuestEventMountsReady, Message: "all disks and shares mounted"})
+	}
+	server.OnGuestEvent = vm.emitGuestEvent
 	return server.Start(ctx)
 }
 
-func (vm *VM) startMachineManagementAPI(ctx context.Context) error {
+func (vm *VM) startMachineManagementAPI(ctx context.Context, forceHostShutdown func()) error {
 	server, err := management.NewServer(managementMachine{
-		Machine: vm.runtime.view,
-		backend: vm.runtime.backend,
-	})
+		Machine:   vm.runtime.view,
+		startedAt: vm.startedAt,
+		events:    vm.observability.stream.server,
+		reporters: &vm.observability.events,
+		health:    &vm.health,
+		lifecycle: &vm.lifecycle,
+		stop:      &vm.stop,
+		ports:     &vm.ports,
+		requestShutdown: func(ctx context.Context) error {
+			vm.emitStopping("management API requested shutdown")
+			return vm.shutdownGuest(ctx)
+		},
+		forceStop: func() {
+			vm.emitStopping("management API requested force stop")
+			forceHostShutdown()
+		},
+	}, vm.cfg.peerAllowlist())
 	if err != nil {
 		return fmt.Errorf("create management server: %w", err)
 	}
@@ -504,11 +607,61 @@ func (vm *VM) startMachineManagementAPI(ctx context.Context) error {
 
 type managementMachine struct {
 	*runtimemachine.Machine
-	backend backend.Backend
+	startedAt       time.Time
+	events          *ssev2.Server
+	reporters       *eventDispatcher
+	health          *vmHealth
+	lifecycle       *vmLifecycle
+	stop            *vmStop
+	ports           *portForwards
+	requestShutdown func(ctx context.Context) error
+	forceStop       func()
 }
 
 func (m managementMachine) RequestShutdown(ctx context.Context) error {
-	return m.backend.RequestShutdown(ctx)
+	return m.requestShutdown(ctx)
+}
+
+// ForceStop takes the same path as a second Ctrl-C: stop the VM without
+// waiting for the guest and tear down host services so Run returns.
+func (m managementMachine) ForceStop(_ context.Context) error {
+	m.forceStop()
+	return nil
+}
+
+func (m managementMachine) Done() <-chan struct{} {
+	return m.stop.doneChan()
+}
+
+func (m managementMachine) StopOutcome() management.StopOutcome {
+	return m.stop.result()
+}
+
+func (m managementMachine) Ports(ctx context.Context) ([]protocol.PortForward, error) {
+	return m.ports.list(ctx, m.Machine)
+}
+
+func (m managementMachine) ExposePort(ctx context.Context, fwd protocol.PortForward) error {
+	return m.ports.expose(ctx, m.Machine, fwd)
+}
+
+func (m managementMachine) UnexposePort(ctx context.Context, fwd protocol.PortForward) error {
+	return m.ports.unexpose(ctx, m.Machine, fwd)
+}
+
+func (m managementMachine) Events() *ssev2.Server {
+	return m.events
+}
+
+func (m managementMachine) EventMetrics() []management.EventReporterMetrics {
+	return m.reporters.metrics()
+}
+
+func (m managementMachine) ManagementView() management.VMConfigView {
+	view := m.Machine.ManagementView()
+	view.StartedAt = m.startedAt
+	view.State = m.lifecycle.view()
+	return view
 }
 
 func (vm *VM) reportPodmanReady(ctx context.Context) error {
@@ -561,7 +714,10 @@ func pingPodman(ctx context.Context, client *network.Client) bool {
 
 func (vm *VM) startShutdownMonitors(ctx context.Context, requestGuestShutdown, forceHostShutdown func()) {
 	// Force shutdown if the launcher disappears and can no longer own cleanup.
-	go vm.monitorLauncherExit(ctx, forceHostShutdown)
+	// A detached launcher is reparented on purpose and owns the VM by itself.
+	if !IsDetachedChild() {
+		go vm.monitorLauncherExit(ctx, forceHostShutdown)
+	}
 
 	go vm.monitorInterrupts(ctx, requestGuestShutdown, forceHostShutdown)
 }
@@ -617,12 +773,31 @@ func waitForShutdownSignal(ctx context.Context, sigCh <-chan os.Signal) (os.Sign
 func (vm *VM) emitStopping(reason string) {
 	logrus.Info(reason)
 	vm.emit(EventStopping, reason)
+	vm.setState(management.StateStopping, reason)
 }
 
 // emit sending events with option msg
 func (vm *VM) emit(kind EventKind, msg string) {
+	vm.emitEvent(kind, msg, "")
+}
+
+func (vm *VM) emitEvent(kind EventKind, msg string, state management.LifecycleState) {
+	if vm == nil || vm.cfg == nil {
+		return
+	}
+	evt := newEvent(vm.cfg.SessionID, vm.cfg.RunMode, kind, msg, vm.seq.Add(1))
+	evt.State = state
+	vm.observability.events.enqueue(evt)
+}
+
+// emitGuestEvent relays an event reported by the guest agent. It is stamped
+// with the host clock, which unlike the guest clock is set from boot.
+func (vm *VM) emitGuestEvent(guestEvt protocol.GuestEvent) {
 	if vm == nil || vm.cfg == nil {
 		return
 	}
-	vm.observability.events.emit(vm.cfg.SessionID, vm.cfg.RunMode, kind, msg, vm.seq.Add(1))
+	evt := newEvent(vm.cfg.SessionID, vm.cfg.RunMode, EventKind(guestEvt.Kind), guestEvt.Message, vm.seq.Add(1))
+	evt.Source = EventSourceGuest
+	evt.ExitCode = guestEvt.ExitCode
+	vm.observability.events.enqueue(evt)
 }
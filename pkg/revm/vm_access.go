@@ -5,13 +5,11 @@ package revm
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"linuxvm/pkg/network"
+	"linuxvm/pkg/client"
 	"linuxvm/pkg/protocol"
 	sshsvc "linuxvm/pkg/service/ssh"
-	"net/http"
 	"path/filepath"
 
 	"al.essio.dev/pkg/shellescape"
@@ -61,26 +59,10 @@ func (vm *VM) Attach(ctx context.Context) error {
 }
 
 func fetchAttachSpec(ctx context.Context, workspaceDirPath string) (protocol.AttachSpec, error) {
-	vmctlAddr := newMachinePathManager(workspaceDirPath).GetVMCtlSocketFile()
-	client := network.NewUnixClient(vmctlAddr)
-	defer client.Close()
+	c := client.New(newMachinePathManager(workspaceDirPath).GetVMCtlSocketFile())
+	defer c.Close()
 
-	body, status, err := client.Get("/v2/attach").DoAndRead(ctx)
-	if err != nil {
-		return protocol.AttachSpec{}, fmt.Errorf("fetch attach spec: %w", err)
-	}
-	if status != http.StatusOK {
-		return protocol.AttachSpec{}, fmt.Errorf("management API returned status %d", status)
-	}
-
-	var spec protocol.AttachSpec
-	if err := json.Unmarshal(body, &spec); err != nil {
-		return protocol.AttachSpec{}, fmt.Errorf("decode attach spec: %w", err)
-	}
-	if spec.SchemaVersion != protocol.AttachSpecVersion {
-		return protocol.AttachSpec{}, fmt.Errorf("unsupported attach spec version: %d", spec.SchemaVersion)
-	}
-	return spec, nil
+	return c.Attach(ctx)
 }
 
 func sshTargetFromAttachSpec(spec protocol.AttachSpec) sshsvc.Target {
@@ -6,9 +6,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"linuxvm/pkg/define"
 	http2 "linuxvm/pkg/http"
 	"linuxvm/pkg/protocol"
 	"net/http"
+	"slices"
 )
 
 type Server struct {
@@ -16,11 +18,22 @@ type Server struct {
 	srv     *http2.Server
 
 	Listening chan struct{}
+	// Version is served to the guest agent; set it before Start.
+	Version protocol.VersionInfo
+	// OnGuestSpec is called each time the guest agent fetches its config.
+	OnGuestSpec func()
+	// OnMountsReady is called when the guest agent reports that all disks
+	// and shares are mounted.
+	OnMountsReady func()
+	// OnGuestEvent is called with each event the guest agent posts.
+	OnGuestEvent func(protocol.GuestEvent)
 }
 
 type Machine interface {
 	IgnitionListenAddr() string
-	GuestSpec() protocol.GuestSpec
+	// GuestSpec returns the guest spec in schema version, which is within
+	// protocol.GuestSpecRange.
+	GuestSpec(version int) protocol.GuestSpec
 }
 
 func NewServer(machine Machine) (*Server, error) {
@@ -40,7 +53,10 @@ func NewServer(machine Machine) (*Server, error) {
 
 func (s *Server) Start(ctx context.Context) error {
 	s.srv.Mux.HandleFunc("/healthz", s.handleHealth)
-	s.srv.Mux.HandleFunc("/vmconfig", s.handleVMConfig)
+	s.srv.Mux.HandleFunc(define.RestAPIVersionURL, s.handleVersion)
+	s.srv.Mux.HandleFunc(define.RestAPIVMConfigURL, s.handleVMConfig)
+	s.srv.Mux.HandleFunc(define.RestAPIMountsReadyURL, s.handleMountsReady)
+	s.srv.Mux.HandleFunc(define.RestAPIGuestEventsURL, s.handleGuestEvent)
 
 	errChan := make(chan error, 2)
 	go func() { errChan <- s.srv.Serve(ctx) }()
@@ -67,11 +83,60 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, nil)
 }
 
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Version)
+}
+
 func (s *Server) handleVMConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, nil)
 		return
 	}
+	version, err := protocol.ParseSchemaVersion(r.URL.Query().Get(protocol.SchemaVersionQuery), protocol.GuestSpecRange)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	writeJSON(w, http.StatusOK, s.machine.GuestSpec())
+	writeJSON(w, http.StatusOK, s.machine.GuestSpec(version))
+	if s.OnGuestSpec != nil {
+		s.OnGuestSpec()
+	}
+}
+
+func (s *Server) handleMountsReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if s.OnMountsReady != nil {
+		s.OnMountsReady()
+	}
+	writeJSON(w, http.StatusOK, nil)
+}
+
+func (s *Server) handleGuestEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	var evt protocol.GuestEvent
+	if err := json.NewDecoder(r.Body).Decode(&evt); err != nil {
+		http.Error(w, fmt.Sprintf("decode guest event: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(protocol.GuestEventKinds, evt.Kind) {
+		http.Error(w, fmt.Sprintf("unknown guest event kind %q", evt.Kind), http.StatusBadRequest)
+		return
+	}
+
+	if s.OnGuestEvent != nil {
+		s.OnGuestEvent(evt)
+	}
+	writeJSON(w, http.StatusOK, nil)
 }
@@ -0,0 +1,63 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"fmt"
+	sshsvc "linuxvm/pkg/service/ssh"
+	"net/http"
+	"path"
+
+	"github.com/sirupsen/logrus"
+)
+
+const tarContentType = "application/x-tar"
+
+// handleCopy moves files between the caller and the guest as tar streams.
+//
+//	GET /v2/cp?path=/guest/path  returns a tar of path, stored under its base name
+//	PUT /v2/cp?path=/guest/dir   extracts the tar request body into dir
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	guestPath := r.URL.Query().Get("path")
+	if guestPath == "" || !path.IsAbs(guestPath) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("path must be an absolute guest path, got %q", guestPath)})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", tarContentType)
+		out := &headerWriter{ResponseWriter: w}
+		err := sshsvc.GuestTarCreate(r.Context(), s.machine.SSHTarget(), guestPath, out)
+		if err == nil {
+			return
+		}
+		if out.wrote {
+			// The status line is gone; cut the stream so the client sees a
+			// truncated archive instead of a valid one.
+			logrus.Warnf("copy from guest %q: %v", guestPath, err)
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Del("Content-Type")
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	case http.MethodPut, http.MethodPost:
+		if err := sshsvc.GuestTarExtract(r.Context(), s.machine.SSHTarget(), guestPath, r.Body); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, nil)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// headerWriter records whether the response has started.
+type headerWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *headerWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
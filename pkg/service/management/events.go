@@ -0,0 +1,68 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"context"
+	ssev2 "linuxvm/pkg/sse"
+	"net/http"
+)
+
+// EventsTopic is the SSE topic that lifecycle events are published to on the
+// server returned by Machine.Events. Each message has the event kind as its
+// type and the JSON encoded event as its data.
+const EventsTopic = "events"
+
+// EventReporterMetrics are the delivery counters of one event reporter,
+// reported by /v2/events/metrics.
+type EventReporterMetrics struct {
+	// Name is the reporter endpoint, or a fixed name for built-in reporters.
+	Name string `json:"name"`
+	// Policy is the overflow policy of the reporter queue.
+	Policy string `json:"policy"`
+	// Accepted counts events queued for the reporter, Delivered those handed
+	// to it.
+	Accepted  uint64 `json:"accepted"`
+	Delivered uint64 `json:"delivered"`
+	// Dropped counts events lost by the queue: overflow with drop-oldest, a
+	// failed spill write, or the flush deadline on close.
+	Dropped uint64 `json:"dropped"`
+	// Spilled counts events that went through the spill file.
+	Spilled uint64 `json:"spilled"`
+	// Failed counts delivered events the reporter itself gave up on, such as
+	// after exhausting its retries.
+	Failed uint64 `json:"failed"`
+	// Pending is the number of events waiting in memory or on disk.
+	Pending int `json:"pending"`
+}
+
+func (s *Server) handleEventMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.machine.EventMetrics())
+}
+
+// handleEvents streams lifecycle events until the client goes away or the
+// management API stops. Events emitted before the client connected are
+// replayed first.
+func (s *Server) handleEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	events := s.machine.Events()
+	if events == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "event stream is not available"})
+		return
+	}
+
+	// http.Server.Shutdown waits for open streams instead of cancelling them.
+	reqCtx, cancel := context.WithCancel(context.WithValue(r.Context(), ssev2.TopicKey, EventsTopic)) //nolint:staticcheck
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	events.ServeHTTP(w, r.WithContext(reqCtx))
+}
@@ -0,0 +1,148 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"linuxvm/pkg/protocol"
+	sshsvc "linuxvm/pkg/service/ssh"
+	ssev2 "linuxvm/pkg/sse"
+	ssh "linuxvm/pkg/ssh"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handleExecV2 streams a guest command as protocol.ExecProtocolVersion events:
+// base64 output chunks followed by a single exit event.
+func (s *Server) handleExecV2(w http.ResponseWriter, r *http.Request, req protocol.ExecRequest) {
+	if req.Bin == "" {
+		http.Error(w, "bin must not be empty", http.StatusBadRequest)
+		return
+	}
+	if err := validateExecEnv(req.Env); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel, err := execTimeout(r.Context(), req.Timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	stream, err := ssev2.NewStream(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exit := s.runExec(ctx, stream, req)
+	data, _ := json.Marshal(exit) //nolint:errchkjson
+	if err := stream.Send(protocol.ExecEventExit, string(data)); err != nil {
+		logrus.Debugf("exec: send exit event: %v", err)
+	}
+}
+
+func (s *Server) runExec(ctx context.Context, stream *ssev2.Stream, req protocol.ExecRequest) protocol.ExecExit {
+	client, err := sshsvc.MakeSSHClient(ctx, s.machine.SSHTarget())
+	if err != nil {
+		return protocol.ExecExit{ExitCode: -1, Error: "ssh connect: " + err.Error()}
+	}
+	defer client.Close()
+
+	cmd := sshsvc.Command{
+		Bin:     req.Bin,
+		Args:    req.Args,
+		Env:     req.Env,
+		WorkDir: req.WorkDir,
+		User:    req.User,
+	}
+	stdout := &execOutputWriter{stream: stream, event: protocol.ExecEventStdout}
+	stderr := &execOutputWriter{stream: stream, event: protocol.ExecEventStderr}
+	// The exit event must be the last one on the stream.
+	defer stdout.close()
+	defer stderr.close()
+
+	err = client.RunWith(ctx, cmd.String(), nil, stdout, stderr)
+
+	return execExitFromError(err, req.Timeout)
+}
+
+// execExitFromError converts the result of a guest command into its exit event.
+func execExitFromError(err error, timeout string) protocol.ExecExit {
+	switch {
+	case err == nil:
+		return protocol.ExecExit{}
+	case errors.Is(err, context.DeadlineExceeded):
+		return protocol.ExecExit{ExitCode: -1, TimedOut: true, Error: "timeout " + timeout + " exceeded"}
+	}
+	if code, signal, ok := ssh.ExitStatus(err); ok {
+		return protocol.ExecExit{ExitCode: code, Signal: signal}
+	}
+	return protocol.ExecExit{ExitCode: -1, Error: err.Error()}
+}
+
+// execTimeout applies the optional timeout of an exec request to ctx.
+func execTimeout(ctx context.Context, value string) (context.Context, context.CancelFunc, error) {
+	if value == "" {
+		return ctx, func() {}, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return nil, nil, fmt.Errorf("invalid timeout %q", value)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+func validateExecEnv(env []string) error {
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("invalid env %q: want KEY=VALUE", kv)
+		}
+	}
+	return nil
+}
+
+// execOutputWriter sends every write as one output event until it is
+// closed.
+type execOutputWriter struct {
+	stream *ssev2.Stream
+	event  string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (w *execOutputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	data, err := json.Marshal(protocol.ExecOutput{Data: p})
+	if err != nil {
+		return 0, err
+	}
+	if err := w.stream.Send(w.event, string(data)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// close drops later writes; a write in progress completes first.
+func (w *execOutputWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+}
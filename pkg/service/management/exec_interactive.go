@@ -0,0 +1,192 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"linuxvm/pkg/protocol"
+	sshsvc "linuxvm/pkg/service/ssh"
+	ssh "linuxvm/pkg/ssh"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handleExecInteractive runs a guest command with stdin attached. The request
+// is upgraded to a protocol.ExecStreamUpgrade connection that carries stdin,
+// window resizes, output and the final exit status as exec frames.
+func (s *Server) handleExecInteractive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), protocol.ExecStreamUpgrade) {
+		http.Error(w, fmt.Sprintf("missing Upgrade: %s header", protocol.ExecStreamUpgrade), http.StatusUpgradeRequired)
+		return
+	}
+	var req protocol.InteractiveExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := validateExecEnv(req.Env); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel, err := execTimeout(r.Context(), req.Timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can not be upgraded", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		logrus.Warnf("exec: hijack connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Upgrade: "+protocol.ExecStreamUpgrade+"\r\n\r\n"); err != nil {
+		logrus.Debugf("exec: write upgrade response: %v", err)
+		return
+	}
+
+	out := &execFrameWriter{conn: conn}
+	exit := s.runInteractiveExec(ctx, buf.Reader, out, req)
+	data, _ := json.Marshal(exit) //nolint:errchkjson
+	if err := out.writeFrame(protocol.ExecFrameExit, data); err != nil {
+		logrus.Debugf("exec: send exit frame: %v", err)
+	}
+}
+
+func (s *Server) runInteractiveExec(ctx context.Context, in io.Reader, out *execFrameWriter, req protocol.InteractiveExecRequest) protocol.ExecExit {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := sshsvc.MakeSSHClient(ctx, s.machine.SSHTarget())
+	if err != nil {
+		return protocol.ExecExit{ExitCode: -1, Error: "ssh connect: " + err.Error()}
+	}
+	defer client.Close()
+
+	opts := ssh.SessionOptions{}
+	if req.Bin != "" || req.WorkDir != "" || req.User != "" || len(req.Env) > 0 {
+		cmd := sshsvc.Command{
+			Bin:     req.Bin,
+			Args:    req.Args,
+			Env:     req.Env,
+			WorkDir: req.WorkDir,
+			User:    req.User,
+		}
+		if cmd.Bin == "" {
+			cmd.Bin = "/bin/sh"
+		}
+		opts.Cmd = cmd.String()
+	}
+	if req.TTY {
+		size := ssh.WindowSize{Width: req.Width, Height: req.Height}
+		if size.Width <= 0 || size.Height <= 0 {
+			size = ssh.WindowSize{Width: 80, Height: 24}
+		}
+		opts.PTY = &size
+	}
+	resize := make(chan ssh.WindowSize, 1)
+	opts.Resize = resize
+
+	stdin, stdinWriter := io.Pipe()
+	go readExecFrames(ctx, cancel, in, stdinWriter, resize)
+
+	err = client.RunInteractive(ctx, opts, stdin,
+		execFrameStream{writer: out, kind: protocol.ExecFrameStdout},
+		execFrameStream{writer: out, kind: protocol.ExecFrameStderr})
+	_ = stdin.Close()
+	return execExitFromError(err, req.Timeout)
+}
+
+// readExecFrames feeds client frames into the session until the client goes
+// away, which cancels the session.
+func readExecFrames(ctx context.Context, cancel context.CancelFunc, in io.Reader, stdin *io.PipeWriter, resize chan ssh.WindowSize) {
+	defer cancel()
+	defer stdin.Close()
+
+	for {
+		kind, payload, err := protocol.ReadExecFrame(in)
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.Debugf("exec: read client frame: %v", err)
+			}
+			return
+		}
+
+		switch kind {
+		case protocol.ExecFrameStdin:
+			if _, err := stdin.Write(payload); err != nil {
+				// The session is gone; keep draining until the exit frame is sent.
+				continue
+			}
+		case protocol.ExecFrameStdinClose:
+			_ = stdin.Close()
+		case protocol.ExecFrameResize:
+			var size protocol.ExecResize
+			if err := json.Unmarshal(payload, &size); err != nil || size.Width <= 0 || size.Height <= 0 {
+				logrus.Debugf("exec: ignore invalid resize frame %q", payload)
+				continue
+			}
+			// Only the latest size matters.
+			select {
+			case <-resize:
+			default:
+			}
+			select {
+			case resize <- ssh.WindowSize{Width: size.Width, Height: size.Height}:
+			default:
+			}
+		default:
+			logrus.Debugf("exec: ignore unknown frame kind %d", kind)
+		}
+	}
+}
+
+// execFrameWriter serializes frames written by the stdout and stderr copiers
+// and the final exit frame.
+type execFrameWriter struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *execFrameWriter) writeFrame(kind byte, payload []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return protocol.WriteExecFrame(w.conn, kind, payload)
+}
+
+// execFrameStream sends every write as one frame of the given kind.
+type execFrameStream struct {
+	writer *execFrameWriter
+	kind   byte
+}
+
+func (s execFrameStream) Write(p []byte) (int, error) {
+	for chunk := p; len(chunk) > 0; {
+		n := min(len(chunk), protocol.MaxExecFramePayload)
+		if err := s.writer.writeFrame(s.kind, chunk[:n]); err != nil {
+			return 0, err
+		}
+		chunk = chunk[n:]
+	}
+	return len(p), nil
+}
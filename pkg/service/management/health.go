@@ -0,0 +1,90 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Components reported by /v2/healthz.
+const (
+	HealthNetwork    = "network"
+	HealthGuestAgent = "guest_agent"
+	HealthMounts     = "mounts"
+	HealthSSH        = "ssh"
+	HealthPodman     = "podman"
+)
+
+const healthPollInterval = 500 * time.Millisecond
+
+// HealthView is the body of /v2/healthz.
+type HealthView struct {
+	// Ready is set when every component is ready.
+	Ready      bool              `json:"ready"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// ComponentHealth is the state of one component. Components that do not
+// apply to the run mode, such as podman in rootfs mode, are not reported.
+type ComponentHealth struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// Message tells what a component that is not ready is waiting for.
+	Message string `json:"message,omitempty"`
+}
+
+// NewHealthView summarizes components.
+func NewHealthView(components []ComponentHealth) HealthView {
+	view := HealthView{Ready: true, Components: components}
+	for _, c := range components {
+		if !c.Ready {
+			view.Ready = false
+		}
+	}
+	return view
+}
+
+// handleHealth reports the HealthView of the machine with status 200 when it
+// is ready and 503 otherwise. With ?wait=<duration> the request is held until
+// the machine is ready or the duration has passed.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid wait value %q", value)})
+			return
+		}
+		wait = parsed
+	}
+
+	ctx := r.Context()
+	deadline := time.Now().Add(wait)
+	ticker := time.NewTicker(healthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		view := s.machine.Health(ctx)
+		if view.Ready {
+			writeJSON(w, http.StatusOK, view)
+			return
+		}
+		if !time.Now().Before(deadline) {
+			writeJSON(w, http.StatusServiceUnavailable, view)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
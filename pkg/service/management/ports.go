@@ -0,0 +1,62 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"encoding/json"
+	"linuxvm/pkg/protocol"
+	"net/http"
+)
+
+// handlePorts manages runtime port forwards.
+//
+//	GET    /v2/ports                          lists forwards
+//	POST   /v2/ports                          exposes the protocol.PortForward body
+//	DELETE /v2/ports?host=...&protocol=tcp    removes a forward
+func (s *Server) handlePorts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ports, err := s.machine.Ports(r.Context())
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, ports)
+	case http.MethodPost:
+		var fwd protocol.PortForward
+		if err := json.NewDecoder(r.Body).Decode(&fwd); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid json"})
+			return
+		}
+		fwd, err := fwd.Normalize()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if fwd.GuestPort == 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "guestPort must not be empty"})
+			return
+		}
+		if err := s.machine.ExposePort(r.Context(), fwd); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, fwd)
+	case http.MethodDelete:
+		fwd, err := protocol.PortForward{
+			Protocol: r.URL.Query().Get("protocol"),
+			Host:     r.URL.Query().Get("host"),
+		}.Normalize()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if err := s.machine.UnexposePort(r.Context(), fwd); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, nil)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+	}
+}
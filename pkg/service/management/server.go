@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	httpv2 "linuxvm/pkg/http"
+	"linuxvm/pkg/network"
 	"linuxvm/pkg/protocol"
 	sshsvc "linuxvm/pkg/service/ssh"
 	ssev2 "linuxvm/pkg/sse"
@@ -23,15 +24,50 @@ type Server struct {
 	machine Machine
 }
 
-type errResponse struct {
+// ErrorResponse is the JSON body of a failed request.
+type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// Routes of the management API.
+const (
+	PathHealth          = "/v2/healthz"
+	PathVMConfig        = "/v2/vmconfig"
+	PathAttach          = "/v2/attach"
+	PathExec            = "/v2/exec"
+	PathExecInteractive = "/v2/exec/interactive"
+	PathStats           = "/v2/stats"
+	PathCopy            = "/v2/cp"
+	PathPorts           = "/v2/ports"
+	PathStop            = "/v2/stop"
+	PathEvents          = "/v2/events"
+	PathVersion         = "/v2/version"
+	PathEventMetrics    = "/v2/events/metrics"
+)
+
 type Machine interface {
 	RequestShutdown(ctx context.Context) error
+	ForceStop(ctx context.Context) error
+	// Done is closed once the VM has ended.
+	Done() <-chan struct{}
+	// StopOutcome tells how the VM ended; it is empty until Done is closed.
+	StopOutcome() StopOutcome
 	ManagementView() VMConfigView
-	AttachSpec() protocol.AttachSpec
+	// Version describes the build; Features.Endpoints is filled by the server.
+	Version() protocol.VersionInfo
+	// Health probes the components the VM needs to be usable.
+	Health(ctx context.Context) HealthView
+	// AttachSpec returns the attach spec in schema version, which is within
+	// protocol.AttachSpecRange.
+	AttachSpec(version int) protocol.AttachSpec
 	SSHTarget() sshsvc.Target
+	// Events returns the lifecycle event stream, see EventsTopic.
+	Events() *ssev2.Server
+	// EventMetrics returns the delivery metrics of every event reporter.
+	EventMetrics() []EventReporterMetrics
+	Ports(ctx context.Context) ([]protocol.PortForward, error)
+	ExposePort(ctx context.Context, fwd protocol.PortForward) error
+	UnexposePort(ctx context.Context, fwd protocol.PortForward) error
 }
 
 func writeJSON(w http.ResponseWriter, code int, value interface{}) {
@@ -40,7 +76,9 @@ func writeJSON(w http.ResponseWriter, code int, value interface{}) {
 	_ = json.NewEncoder(w).Encode(value) //nolint:errchkjson
 }
 
-func NewServer(machine Machine) (*Server, error) {
+// NewServer creates the management API server of machine. Only local users
+// admitted by peers may connect: exec gives root in the guest.
+func NewServer(machine Machine, peers network.PeerAllowlist) (*Server, error) {
 	if machine == nil {
 		return nil, fmt.Errorf("machine is nil")
 	}
@@ -48,20 +86,31 @@ func NewServer(machine Machine) (*Server, error) {
 	if config.Endpoints.ManagementAPI == "" {
 		return nil, fmt.Errorf("management API endpoint is empty")
 	}
+	srv := httpv2.NewUnixSockHTTPServer("management-api", config.Endpoints.ManagementAPI)
+	srv.Peers = &peers
 	return &Server{
 		machine: machine,
-		srv:     httpv2.NewUnixSockHTTPServer("management-api", config.Endpoints.ManagementAPI),
+		srv:     srv,
 		sse:     ssev2.NewSSEServer(),
 	}, nil
 }
 
 func (s *Server) Start(ctx context.Context) error {
 	// new management api
-	s.srv.Mux.HandleFunc("/v2/healthz", s.handleHealth)
-	s.srv.Mux.HandleFunc("/v2/vmconfig", s.handleVMConfig)
-	s.srv.Mux.HandleFunc("/v2/attach", s.handleAttach)
-	s.srv.Mux.HandleFunc("/v2/exec", s.handleExec)
-	s.srv.Mux.HandleFunc("/v2/stop", s.handleRequestVMStop)
+	s.srv.Mux.HandleFunc(PathVersion, s.handleVersion)
+	s.srv.Mux.HandleFunc(PathHealth, s.handleHealth)
+	s.srv.Mux.HandleFunc(PathVMConfig, s.handleVMConfig)
+	s.srv.Mux.HandleFunc(PathAttach, s.handleAttach)
+	s.srv.Mux.HandleFunc(PathExec, s.handleExec)
+	s.srv.Mux.HandleFunc(PathExecInteractive, s.handleExecInteractive)
+	s.srv.Mux.HandleFunc(PathStats, s.handleStats)
+	s.srv.Mux.HandleFunc(PathCopy, s.handleCopy)
+	s.srv.Mux.HandleFunc(PathPorts, s.handlePorts)
+	s.srv.Mux.HandleFunc(PathStop, s.handleRequestVMStop)
+	s.srv.Mux.HandleFunc(PathEventMetrics, s.handleEventMetrics)
+	s.srv.Mux.HandleFunc(PathEvents, func(w http.ResponseWriter, r *http.Request) {
+		s.handleEvents(ctx, w, r)
+	})
 
 	return s.srv.Serve(ctx)
 }
@@ -73,14 +122,6 @@ type Info struct {
 	HostDNSInGVPNetwork  string `json:"hostEndpoint"`
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, nil)
-		return
-	}
-	writeJSON(w, http.StatusOK, nil)
-}
-
 func (s *Server) handleVMConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, nil)
@@ -94,22 +135,12 @@ func (s *Server) handleAttach(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusMethodNotAllowed, nil)
 		return
 	}
-	writeJSON(w, http.StatusOK, s.machine.AttachSpec())
-}
-
-func (s *Server) handleRequestVMStop(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet && r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, nil)
+	version, err := protocol.ParseSchemaVersion(r.URL.Query().Get(protocol.SchemaVersionQuery), protocol.AttachSpecRange)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	_ = s.machine.RequestShutdown(r.Context())
-	writeJSON(w, http.StatusOK, nil)
-}
-
-type execRequest struct {
-	Bin  string   `json:"bin,omitempty"`
-	Args []string `json:"args,omitempty"`
-	Env  []string `json:"env,omitempty"`
+	writeJSON(w, http.StatusOK, s.machine.AttachSpec(version))
 }
 
 func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
@@ -117,11 +148,19 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	var req execRequest
+	var req protocol.ExecRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
+	switch {
+	case req.Version == protocol.ExecProtocolVersion:
+		s.handleExecV2(w, r, req)
+		return
+	case req.Version > 1:
+		http.Error(w, fmt.Sprintf("unsupported exec protocol version %d", req.Version), http.StatusBadRequest)
+		return
+	}
 	topic := "sess-" + uuid.NewString()
 	ctx, cancel := context.WithCancel(context.WithValue(r.Context(), ssev2.TopicKey, topic)) //nolint:staticcheck
 	defer cancel()
@@ -129,7 +168,7 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 	s.sse.ServeHTTP(w, r.WithContext(ctx))
 }
 
-func (s *Server) executeCommand(ctx context.Context, cancel context.CancelFunc, topic string, req execRequest) {
+func (s *Server) executeCommand(ctx context.Context, cancel context.CancelFunc, topic string, req protocol.ExecRequest) {
 	defer cancel()
 	proc, err := sshsvc.GuestExec(ctx, s.machine.SSHTarget(), req.Bin, req.Args...)
 	if err != nil {
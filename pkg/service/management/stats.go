@@ -0,0 +1,107 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"linuxvm/pkg/define"
+	"linuxvm/pkg/protocol"
+	sshsvc "linuxvm/pkg/service/ssh"
+	ssev2 "linuxvm/pkg/sse"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	statsEvent           = "stats"
+	defaultStatsInterval = 2 * time.Second
+	minStatsInterval     = 100 * time.Millisecond
+)
+
+// handleStats returns one protocol.GuestStats sample collected by the guest
+// agent. With ?stream=true it keeps sending samples as "stats" SSE events,
+// one per ?interval= (default 2s), until the client goes away.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	query := r.URL.Query()
+	stream := false
+	if value := query.Get("stream"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid stream value %q", value)})
+			return
+		}
+		stream = parsed
+	}
+	interval := defaultStatsInterval
+	if value := query.Get("interval"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < minStatsInterval {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid interval %q, want a duration of at least %s", value, minStatsInterval)})
+			return
+		}
+		interval = parsed
+	}
+
+	client, err := sshsvc.MakeSSHClient(r.Context(), s.machine.SSHTarget())
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "ssh connect: " + err.Error()})
+		return
+	}
+	defer client.Close()
+
+	cmd := sshsvc.Command{Bin: define.GuestAgentPathInGuest, Args: []string{protocol.GuestStatsCommand}}
+	if !stream {
+		var stdout, stderr bytes.Buffer
+		if err := client.RunWith(r.Context(), cmd.String(), nil, &stdout, &stderr); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("collect guest stats: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))})
+			return
+		}
+		var stats protocol.GuestStats
+		if err := json.Unmarshal(stdout.Bytes(), &stats); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "decode guest stats: " + err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	sse, err := ssev2.NewStream(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cmd.Args = append(cmd.Args, "--"+protocol.GuestStatsIntervalFlag, interval.String())
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if err := sse.Send(statsEvent, scanner.Text()); err != nil {
+				_ = reader.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	err = client.RunWith(r.Context(), cmd.String(), nil, writer, nil)
+	_ = writer.Close()
+	<-done
+	if err != nil && r.Context().Err() == nil {
+		logrus.Debugf("stats: guest agent exited: %v", err)
+		_ = sse.Send(ssev2.TypeErr, "collect guest stats: "+err.Error())
+	}
+}
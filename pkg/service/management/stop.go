@@ -0,0 +1,132 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StopOutcome tells how a VM ended.
+type StopOutcome string
+
+const (
+	// StopGuest: the guest shut down without being asked.
+	StopGuest StopOutcome = "guest"
+	// StopGraceful: the guest shut down after a stop request.
+	StopGraceful StopOutcome = "graceful"
+	// StopForced: the VMM was stopped without the guest, on request, after
+	// the graceful timeout or because a host service failed.
+	StopForced StopOutcome = "forced"
+)
+
+const forceStopTimeout = 5 * time.Second
+
+// StopResult is the body of /v2/stop.
+type StopResult struct {
+	// Stopped is set when the VM has ended before the answer was written,
+	// which is always the case with wait.
+	Stopped bool `json:"stopped"`
+	// Outcome tells how the VM ended; empty unless Stopped.
+	Outcome StopOutcome `json:"outcome,omitempty"`
+}
+
+// handleRequestVMStop stops the VM:
+//   - force=true stops the VMM at once without involving the guest.
+//   - Otherwise the guest is asked to shut down; with timeout=<duration> the
+//     server forces the stop if the guest has not shut down in time, even if
+//     the client has gone away meanwhile.
+//   - wait=true holds the request until the VM has ended and reports how.
+func (s *Server) handleRequestVMStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	query := r.URL.Query()
+	force, err := boolParam(query.Get("force"), "force")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	wait, err := boolParam(query.Get("wait"), "wait")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	var timeout time.Duration
+	if value := query.Get("timeout"); value != "" {
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("invalid timeout value %q", value)})
+			return
+		}
+	}
+
+	if force {
+		if err := s.machine.ForceStop(r.Context()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("force stop: %v", err)})
+			return
+		}
+	} else {
+		if err := s.machine.RequestShutdown(r.Context()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("request guest shutdown: %v", err)})
+			return
+		}
+		if timeout > 0 {
+			go s.forceStopAfter(timeout)
+		}
+	}
+
+	if wait {
+		select {
+		case <-s.machine.Done():
+		case <-r.Context().Done():
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.stopResult())
+}
+
+// forceStopAfter forces the stop if the VM is still running after timeout.
+func (s *Server) forceStopAfter(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.machine.Done():
+		return
+	case <-timer.C:
+	}
+	logrus.Warnf("guest did not shut down within %s, forcing stop", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), forceStopTimeout)
+	defer cancel()
+	if err := s.machine.ForceStop(ctx); err != nil {
+		logrus.Warnf("force stop after timeout failed: %v", err)
+	}
+}
+
+func (s *Server) stopResult() StopResult {
+	select {
+	case <-s.machine.Done():
+		return StopResult{Stopped: true, Outcome: s.machine.StopOutcome()}
+	default:
+		return StopResult{}
+	}
+}
+
+func boolParam(value, name string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q", name, value)
+	}
+	return parsed, nil
+}
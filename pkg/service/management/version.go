@@ -0,0 +1,34 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package management
+
+import (
+	"net/http"
+)
+
+// Endpoints lists the routes registered by Server.Start, reported in
+// /v2/version so that clients can tell what an older host lacks.
+var Endpoints = []string{
+	PathVersion,
+	PathHealth,
+	PathVMConfig,
+	PathAttach,
+	PathExec,
+	PathExecInteractive,
+	PathStats,
+	PathCopy,
+	PathPorts,
+	PathStop,
+	PathEvents,
+	PathEventMetrics,
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	info := s.machine.Version()
+	info.Features.Endpoints = Endpoints
+	writeJSON(w, http.StatusOK, info)
+}
@@ -2,6 +2,8 @@
 
 package management
 
+import "time"
+
 type VMConfigView struct {
 	RunMode     string       `json:"runMode,omitempty"`
 	NetworkMode string       `json:"networkMode,omitempty"`
@@ -10,6 +12,46 @@ type VMConfigView struct {
 	TTY         bool         `json:"tty"`
 	Mounts      []MountView  `json:"mounts,omitempty"`
 	Disks       []DiskView   `json:"disks,omitempty"`
+	StartedAt   time.Time    `json:"startedAt"`
+	State       StateView    `json:"state"`
+}
+
+// LifecycleState is a stage in the life of a VM session. States only move
+// forward, in the order listed; stopped and failed are final.
+type LifecycleState string
+
+const (
+	// StateBuilding: the workspace, disks and backend are being prepared.
+	StateBuilding LifecycleState = "building"
+	// StateBooting: host services are starting and the VM is booting.
+	StateBooting LifecycleState = "booting"
+	// StateNetworkReady: the host network stack is up.
+	StateNetworkReady LifecycleState = "network_ready"
+	// StateGuestReady: the guest agent runs and has fetched its config.
+	StateGuestReady LifecycleState = "guest_ready"
+	// StateRunning: the guest agent mounted everything and starts the workload.
+	StateRunning LifecycleState = "running"
+	// StateStopping: a shutdown was requested.
+	StateStopping LifecycleState = "stopping"
+	// StateStopped: the VM exited.
+	StateStopped LifecycleState = "stopped"
+	// StateFailed: a host service or the VM failed; see StateView.Cause.
+	StateFailed LifecycleState = "failed"
+)
+
+// StateView is the lifecycle state of a VM with the history that led to it.
+type StateView struct {
+	State LifecycleState `json:"state"`
+	// Cause explains a stopping or failed state.
+	Cause       string            `json:"cause,omitempty"`
+	Transitions []StateTransition `json:"transitions"`
+}
+
+// StateTransition records when a state was entered.
+type StateTransition struct {
+	State LifecycleState `json:"state"`
+	Time  time.Time      `json:"time"`
+	Cause string         `json:"cause,omitempty"`
 }
 
 type ResourceView struct {
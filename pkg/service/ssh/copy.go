@@ -0,0 +1,58 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"linuxvm/pkg/define"
+	"path"
+
+	"al.essio.dev/pkg/shellescape"
+)
+
+// GuestTarCreate writes a tar stream of the guest path p to w. The archive
+// holds p under its base name and keeps modes, ownership and symlinks.
+func GuestTarCreate(ctx context.Context, target Target, p string, w io.Writer) error {
+	if !path.IsAbs(p) {
+		return fmt.Errorf("guest path %q must be absolute", p)
+	}
+	dir, name := path.Split(path.Clean(p))
+	if name == "" {
+		// The guest root itself.
+		dir, name = "/", "."
+	}
+	return runGuestTar(ctx, target, nil, w,
+		shellescape.QuoteCommand([]string{define.BuiltinBusybox, "tar", "-C", dir, "-cf", "-", name}))
+}
+
+// GuestTarExtract unpacks the tar stream r into the guest directory dir,
+// creating it if needed. Modes and ownership are restored.
+func GuestTarExtract(ctx context.Context, target Target, dir string, r io.Reader) error {
+	if !path.IsAbs(dir) {
+		return fmt.Errorf("guest path %q must be absolute", dir)
+	}
+	dir = path.Clean(dir)
+	return runGuestTar(ctx, target, r, nil,
+		shellescape.QuoteCommand([]string{define.BuiltinBusybox, "mkdir", "-p", dir})+" && exec "+
+			shellescape.QuoteCommand([]string{define.BuiltinBusybox, "tar", "-C", dir, "-xpf", "-"}))
+}
+
+func runGuestTar(ctx context.Context, target Target, stdin io.Reader, stdout io.Writer, cmd string) error {
+	client, err := MakeSSHClient(ctx, target)
+	if err != nil {
+		return fmt.Errorf("ssh connect: %w", err)
+	}
+	defer client.Close()
+
+	var stderr bytes.Buffer
+	if err := client.RunWith(ctx, cmd, stdin, stdout, &stderr); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return fmt.Errorf("guest tar: %w: %s", err, msg)
+		}
+		return fmt.Errorf("guest tar: %w", err)
+	}
+	return nil
+}
@@ -28,6 +28,30 @@ type Target struct {
 	GuestTunnelHost          string
 }
 
+// Command describes a guest command run through the guest SSH server.
+type Command struct {
+	Bin     string
+	Args    []string
+	Env     []string
+	WorkDir string
+	User    string
+}
+
+// String renders c as a command line for the guest shell.
+func (c Command) String() string {
+	line := shellescape.QuoteCommand(append([]string{c.Bin}, c.Args...))
+	if len(c.Env) > 0 {
+		line = "env " + shellescape.QuoteCommand(c.Env) + " " + line
+	}
+	if c.WorkDir != "" {
+		line = "cd " + shellescape.Quote(c.WorkDir) + " && exec " + line
+	}
+	if c.User != "" {
+		line = "exec su -s /bin/sh " + shellescape.Quote(c.User) + " -c " + shellescape.Quote(line)
+	}
+	return line
+}
+
 func GuestExec(ctx context.Context, target Target, bin string, args ...string) (*ProcessOutput, error) {
 	sshClient, err := MakeSSHClient(ctx, target)
 	if err != nil {
@@ -0,0 +1,92 @@
+//go:build (darwin && arm64) || (linux && (arm64 || amd64))
+
+package sse
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// NewReplayServer returns a Server that keeps the last history published
+// messages. A new subscriber first receives the kept messages of its topic,
+// or only the ones after Last-Event-ID when it reconnects with that header.
+func NewReplayServer(history int) *Server {
+	s := NewSSEServer()
+	s.server.Provider = &sse.Joe{Replayer: &historyReplayer{size: history}}
+	return s
+}
+
+// Shutdown disconnects all subscribers. Publish does nothing afterwards.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// historyReplayer numbers messages from 1 and, unlike the go-sse replayers,
+// replays everything to subscribers that send no Last-Event-ID.
+type historyReplayer struct {
+	mu       sync.Mutex
+	size     int
+	lastID   uint64
+	messages []replayMessage
+}
+
+type replayMessage struct {
+	id      uint64
+	message *sse.Message
+	topics  []string
+}
+
+func (h *historyReplayer) Put(message *sse.Message, topics []string) (*sse.Message, error) {
+	if len(topics) == 0 {
+		return nil, sse.ErrNoTopic
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	message = message.Clone()
+	message.ID = sse.ID(strconv.FormatUint(h.lastID, 10))
+
+	h.messages = append(h.messages, replayMessage{id: h.lastID, message: message, topics: topics})
+	if h.size > 0 && len(h.messages) > h.size {
+		h.messages = slices.Delete(h.messages, 0, len(h.messages)-h.size)
+	}
+	return message, nil
+}
+
+func (h *historyReplayer) Replay(subscription sse.Subscription) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var after uint64
+	if subscription.LastEventID.IsSet() {
+		id, err := strconv.ParseUint(subscription.LastEventID.String(), 10, 64)
+		if err != nil {
+			// Not one of ours: replay nothing, as the go-sse replayers do.
+			return nil
+		}
+		after = id
+	}
+
+	sent := false
+	for _, m := range h.messages {
+		if m.id <= after || !slices.ContainsFunc(m.topics, func(t string) bool {
+			return slices.Contains(subscription.Topics, t)
+		}) {
+			continue
+		}
+		if err := subscription.Client.Send(m.message); err != nil {
+			return err
+		}
+		sent = true
+	}
+	if !sent {
+		return nil
+	}
+	return subscription.Client.Flush()
+}
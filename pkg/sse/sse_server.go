@@ -4,6 +4,7 @@ package sse
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tmaxmax/go-sse"
@@ -50,3 +51,32 @@ func (s *Server) Publish(topic, msgType, data string) {
 		logrus.Warnf("sse: failed to publish message: %v", err)
 	}
 }
+
+// Stream writes events straight to a single client. Unlike Server.Publish,
+// nothing sent before the client starts reading can be lost.
+type Stream struct {
+	mu      sync.Mutex
+	session *sse.Session
+}
+
+func NewStream(w http.ResponseWriter, r *http.Request) (*Stream, error) {
+	session, err := sse.Upgrade(w, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{session: session}, nil
+}
+
+// Send writes one event and flushes it to the client.
+func (s *Stream) Send(msgType, data string) error {
+	msg := &sse.Message{}
+	msg.AppendData(data)
+	msg.Type = sse.Type(msgType)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.session.Send(msg); err != nil {
+		return err
+	}
+	return s.session.Flush()
+}
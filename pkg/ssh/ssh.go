@@ -25,6 +25,7 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -224,8 +225,14 @@ func (c *Client) Run(ctx context.Context, cmd string) error {
 	return c.RunWith(ctx, cmd, nil, os.Stdout, os.Stderr)
 }
 
+// runStopGrace is how long RunWith waits for a command to exit after
+// SIGTERM before it closes the session.
+const runStopGrace = 2 * time.Second
+
 // RunWith executes a command with custom I/O streams.
-// Any of stdin, stdout, stderr can be nil.
+// Any of stdin, stdout, stderr can be nil. When ctx is done the command gets
+// SIGTERM; RunWith returns ctx.Err() once the session has ended, so stdout
+// and stderr are no longer written to after it returns.
 func (c *Client) RunWith(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if c.isClosed() {
 		return ErrClientClosed
@@ -248,10 +255,18 @@ func (c *Client) RunWith(ctx context.Context, cmd string, stdin io.Reader, stdou
 	select {
 	case <-ctx.Done():
 		_ = session.Signal(ssh.SIGTERM)
-		return ctx.Err()
 	case err := <-errCh:
 		return err
 	}
+
+	select {
+	case <-errCh:
+	case <-time.After(runStopGrace):
+		// Closing the channel ends session.Run and its copy goroutines.
+		_ = session.Close()
+		<-errCh
+	}
+	return ctx.Err()
 }
 
 // Output executes a command and returns its stdout.
@@ -297,57 +312,92 @@ func (c *Client) ShellWith(ctx context.Context, stdin io.Reader, stdout, stderr
 		return ErrClientClosed
 	}
 
-	session, err := c.sshClient.NewSession()
-	if err != nil {
-		return fmt.Errorf("create session: %w", err)
-	}
-	defer session.Close()
-
 	// Get terminal size
-	width, height := 80, 24
+	size := WindowSize{Width: 80, Height: 24}
 	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
 		if w, h, err := term.GetSize(int(f.Fd())); err == nil {
-			width, height = w, h
+			size = WindowSize{Width: w, Height: h}
 		}
 	}
 
-	// Request PTY
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          1,
-		ssh.TTY_OP_ISPEED: 14400,
-		ssh.TTY_OP_OSPEED: 14400,
-	}
-	if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
-		return fmt.Errorf("request pty: %w", err)
-	}
+	opts := SessionOptions{PTY: &size}
 
 	// Set raw mode if stdin is a terminal
-	var restoreFunc func()
-	var resizeDone chan struct{}
-
 	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
 		oldState, err := term.MakeRaw(int(f.Fd()))
 		if err != nil {
 			return fmt.Errorf("set raw mode: %w", err)
 		}
-		restoreFunc = func() {
+		defer func() {
 			if err = term.Restore(int(f.Fd()), oldState); err != nil {
 				logrus.Warnf("restore terminal state: %v", err)
 			}
-		}
-		defer restoreFunc()
+		}()
 
 		// Handle window resize with done channel
-		resizeDone = make(chan struct{})
-		go c.watchResize(ctx, session, f, resizeDone)
+		resize := make(chan WindowSize, 1)
+		resizeDone := make(chan struct{})
+		defer close(resizeDone)
+		go c.watchResize(ctx, f, resize, resizeDone)
+		opts.Resize = resize
+	}
+
+	return c.RunInteractive(ctx, opts, stdin, stdout, stderr)
+}
+
+// WindowSize is a terminal size in character cells.
+type WindowSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// SessionOptions configures RunInteractive.
+type SessionOptions struct {
+	// Cmd is the command to run; empty starts the login shell.
+	Cmd string
+	// PTY requests a pseudo-terminal of the given initial size; nil runs
+	// without one and keeps stdout and stderr apart.
+	PTY *WindowSize
+	// Resize delivers window size changes for the PTY.
+	Resize <-chan WindowSize
+}
+
+// RunInteractive runs a session with stdin attached and, optionally, a PTY
+// whose size follows opts.Resize. It blocks until the remote command exits.
+func (c *Client) RunInteractive(ctx context.Context, opts SessionOptions, stdin io.Reader, stdout, stderr io.Writer) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	session, err := c.sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+
+	// Request PTY
+	if opts.PTY != nil {
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty("xterm-256color", opts.PTY.Height, opts.PTY.Width, modes); err != nil {
+			return fmt.Errorf("request pty: %w", err)
+		}
 	}
 
 	session.Stdin = stdin
 	session.Stdout = stdout
 	session.Stderr = stderr
 
-	if err := session.Shell(); err != nil {
-		return fmt.Errorf("start shell: %w", err)
+	if opts.Cmd == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(opts.Cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("start session: %w", err)
 	}
 
 	errCh := make(chan error, 1)
@@ -355,24 +405,26 @@ func (c *Client) ShellWith(ctx context.Context, stdin io.Reader, stdout, stderr
 		errCh <- session.Wait()
 	}()
 
-	var result error
-	select {
-	case <-ctx.Done():
-		_ = session.Signal(ssh.SIGTERM)
-		result = ctx.Err()
-	case err := <-errCh:
-		result = err
-	}
-
-	// Signal watchResize to stop
-	if resizeDone != nil {
-		close(resizeDone)
+	for {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGTERM)
+			return ctx.Err()
+		case err := <-errCh:
+			return err
+		case size, ok := <-opts.Resize:
+			if !ok {
+				opts.Resize = nil
+				continue
+			}
+			if opts.PTY != nil {
+				_ = session.WindowChange(size.Height, size.Width)
+			}
+		}
 	}
-
-	return result
 }
 
-func (c *Client) watchResize(ctx context.Context, session *ssh.Session, f *os.File, done <-chan struct{}) {
+func (c *Client) watchResize(ctx context.Context, f *os.File, resize chan<- WindowSize, done <-chan struct{}) {
 	sigCh := make(chan os.Signal, 1)
 	signalNotify(sigCh)
 	defer signalStop(sigCh)
@@ -387,7 +439,11 @@ func (c *Client) watchResize(ctx context.Context, session *ssh.Session, f *os.Fi
 			return
 		case <-sigCh:
 			if w, h, err := term.GetSize(int(f.Fd())); err == nil {
-				_ = session.WindowChange(h, w)
+				select {
+				case resize <- WindowSize{Width: w, Height: h}:
+				case <-done:
+					return
+				}
 			}
 		}
 	}
@@ -407,3 +463,13 @@ func (c *Client) Close() error {
 	})
 	return nil
 }
+
+// ExitStatus extracts the remote exit status from an error returned by Run,
+// RunWith or Output. ok is false if err does not carry one.
+func ExitStatus(err error) (code int, signal string, ok bool) {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), exitErr.Signal(), true
+	}
+	return 0, "", false
+}
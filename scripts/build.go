@@ -19,6 +19,11 @@ import (
 
 const assetsBase = "https://github.com/ihexon/revm-assets/releases/download/v2.0.22"
 
+// assetsRelease is the release tag of assetsBase, which pins libkrun.
+func assetsRelease() string {
+	return assetsBase[strings.LastIndex(assetsBase, "/")+1:]
+}
+
 var defaultBuildTargets = []string{"chroot", "dockerd"}
 
 // Edit this table when revm-assets changes.
@@ -278,8 +283,8 @@ func (b *builder) buildBundles() error {
 	commit := commandOutput("unknown", "git", "-C", b.workspace, "rev-parse", "--short", "HEAD")
 	buildDate := time.Now().UTC().Format("20060102T150405Z")
 	ldflags := fmt.Sprintf(
-		"-X linuxvm/pkg/define.Version=%s -X linuxvm/pkg/define.CommitID=%s -X linuxvm/pkg/define.BuildDate=%s",
-		version, commit, buildDate,
+		"-X linuxvm/pkg/define.Version=%s -X linuxvm/pkg/define.CommitID=%s -X linuxvm/pkg/define.BuildDate=%s -X linuxvm/pkg/define.AssetsRelease=%s",
+		version, commit, buildDate, assetsRelease(),
 	)
 	if b.goos == "linux" {
 		ldflags += ` -linkmode=external -extldflags "-static-libgcc -static-libstdc++"`